@@ -0,0 +1,307 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package adjoint provides numbers recording their operations on a tape,
+// used by autofd to generate reverse mode derivatives.
+package adjoint // import "gonum.org/v1/tools/autofd/adjoint"
+
+import "math"
+
+// Number is a real number whose operations may be recorded on a tape.
+// Numbers that are not variables of a tape, nor computed from such variables,
+// are constants: Number{Real: c} is the constant c.
+type Number struct {
+	Real float64
+
+	tape *Tape
+	id   int
+}
+
+// Tape records the operations applied to its variables.
+// The zero value is an empty tape ready to use.
+type Tape struct {
+	nodes []node
+	vars  []int
+	adj   []float64
+}
+
+// node is an operation recorded on a tape, with the partial derivatives
+// of its result with respect to its operands.
+type node struct {
+	args     [2]int // indices of the operands, -1 if none.
+	partials [2]float64
+}
+
+// Var returns a new variable of the tape with value x.
+func (t *Tape) Var(x float64) Number {
+	t.vars = append(t.vars, len(t.nodes))
+	return t.push(x, node{args: [2]int{-1, -1}})
+}
+
+// Gradient stores in grad the gradient of y with respect to the variables
+// of the tape, in their order of creation. Gradient panics if the length of
+// grad is not the number of variables.
+//
+// Gradient performs a single reverse sweep over the operations recorded
+// on the tape before y. It may be called with several numbers of the same
+// tape, to compute the rows of a Jacobian.
+func (t *Tape) Gradient(grad []float64, y Number) {
+	if len(grad) != len(t.vars) {
+		panic("adjoint: slice length mismatch")
+	}
+	for i := range grad {
+		grad[i] = 0
+	}
+	if y.tape == nil {
+		return
+	}
+	if y.tape != t {
+		panic("adjoint: number not recorded on tape")
+	}
+
+	if cap(t.adj) < y.id+1 {
+		t.adj = make([]float64, y.id+1)
+	}
+	adj := t.adj[:y.id+1]
+	for i := range adj {
+		adj[i] = 0
+	}
+	adj[y.id] = 1
+	for i := y.id; i >= 0; i-- {
+		if adj[i] == 0 {
+			continue
+		}
+		n := t.nodes[i]
+		for j, arg := range n.args {
+			if arg >= 0 {
+				adj[arg] += adj[i] * n.partials[j]
+			}
+		}
+	}
+	for i, id := range t.vars {
+		if id <= y.id {
+			grad[i] = adj[id]
+		}
+	}
+}
+
+// push records the provided operation with result v.
+func (t *Tape) push(v float64, n node) Number {
+	t.nodes = append(t.nodes, n)
+	return Number{Real: v, tape: t, id: len(t.nodes) - 1}
+}
+
+// unary returns the result v of an operation on x,
+// with dx the derivative of v with respect to x.
+func unary(v float64, x Number, dx float64) Number {
+	if x.tape == nil {
+		return Number{Real: v}
+	}
+	return x.tape.push(v, node{args: [2]int{x.id, -1}, partials: [2]float64{dx, 0}})
+}
+
+// binary returns the result v of an operation on x and y,
+// with dx and dy the partial derivatives of v with respect to x and y.
+func binary(v float64, x, y Number, dx, dy float64) Number {
+	t := x.tape
+	switch {
+	case t == nil:
+		t = y.tape
+	case y.tape != nil && y.tape != t:
+		panic("adjoint: mixed tapes")
+	}
+	if t == nil {
+		return Number{Real: v}
+	}
+	n := node{args: [2]int{-1, -1}}
+	if x.tape != nil {
+		n.args[0], n.partials[0] = x.id, dx
+	}
+	if y.tape != nil {
+		n.args[1], n.partials[1] = y.id, dy
+	}
+	return t.push(v, n)
+}
+
+// Add returns the sum of x and y.
+func Add(x, y Number) Number {
+	return binary(x.Real+y.Real, x, y, 1, 1)
+}
+
+// Sub returns the difference of x and y, x-y.
+func Sub(x, y Number) Number {
+	return binary(x.Real-y.Real, x, y, 1, -1)
+}
+
+// Mul returns the product of x and y.
+func Mul(x, y Number) Number {
+	return binary(x.Real*y.Real, x, y, y.Real, x.Real)
+}
+
+// Scale returns x scaled by f.
+func Scale(f float64, x Number) Number {
+	return unary(f*x.Real, x, f)
+}
+
+// Inv returns the reciprocal of x.
+func Inv(x Number) Number {
+	v := 1 / x.Real
+	return unary(v, x, -v*v)
+}
+
+// Abs returns the absolute value of x.
+func Abs(x Number) Number {
+	s := math.Copysign(1, x.Real)
+	return unary(s*x.Real, x, s)
+}
+
+// Exp returns e**x, the base-e exponential of x.
+func Exp(x Number) Number {
+	v := math.Exp(x.Real)
+	return unary(v, x, v)
+}
+
+// Log returns the natural logarithm of x.
+func Log(x Number) Number {
+	return unary(math.Log(x.Real), x, 1/x.Real)
+}
+
+// Pow returns x**p, the base-x exponential of p.
+func Pow(x, p Number) Number {
+	if p.tape == nil {
+		return PowReal(x, p.Real)
+	}
+	v := math.Pow(x.Real, p.Real)
+	return binary(v, x, p, p.Real*math.Pow(x.Real, p.Real-1), v*math.Log(x.Real))
+}
+
+// PowReal returns x**p, the base-x exponential of p.
+func PowReal(x Number, p float64) Number {
+	return unary(math.Pow(x.Real, p), x, p*math.Pow(x.Real, p-1))
+}
+
+// Sqrt returns the square root of x.
+func Sqrt(x Number) Number {
+	v := math.Sqrt(x.Real)
+	return unary(v, x, 0.5/v)
+}
+
+// Sin returns the sine of x.
+func Sin(x Number) Number {
+	s, c := math.Sincos(x.Real)
+	return unary(s, x, c)
+}
+
+// Cos returns the cosine of x.
+func Cos(x Number) Number {
+	s, c := math.Sincos(x.Real)
+	return unary(c, x, -s)
+}
+
+// Tan returns the tangent of x.
+func Tan(x Number) Number {
+	v := math.Tan(x.Real)
+	return unary(v, x, 1+v*v)
+}
+
+// Sinh returns the hyperbolic sine of x.
+func Sinh(x Number) Number {
+	return unary(math.Sinh(x.Real), x, math.Cosh(x.Real))
+}
+
+// Cosh returns the hyperbolic cosine of x.
+func Cosh(x Number) Number {
+	return unary(math.Cosh(x.Real), x, math.Sinh(x.Real))
+}
+
+// Tanh returns the hyperbolic tangent of x.
+func Tanh(x Number) Number {
+	v := math.Tanh(x.Real)
+	return unary(v, x, 1-v*v)
+}
+
+// Asin returns the inverse sine of x.
+func Asin(x Number) Number {
+	return unary(math.Asin(x.Real), x, 1/math.Sqrt(1-x.Real*x.Real))
+}
+
+// Acos returns the inverse cosine of x.
+func Acos(x Number) Number {
+	return unary(math.Acos(x.Real), x, -1/math.Sqrt(1-x.Real*x.Real))
+}
+
+// Atan returns the inverse tangent of x.
+func Atan(x Number) Number {
+	return unary(math.Atan(x.Real), x, 1/(1+x.Real*x.Real))
+}
+
+// Asinh returns the inverse hyperbolic sine of x.
+func Asinh(x Number) Number {
+	return unary(math.Asinh(x.Real), x, 1/math.Sqrt(x.Real*x.Real+1))
+}
+
+// Acosh returns the inverse hyperbolic cosine of x.
+func Acosh(x Number) Number {
+	return unary(math.Acosh(x.Real), x, 1/math.Sqrt(x.Real*x.Real-1))
+}
+
+// Atanh returns the inverse hyperbolic tangent of x.
+func Atanh(x Number) Number {
+	return unary(math.Atanh(x.Real), x, 1/(1-x.Real*x.Real))
+}
+
+// Atan2 returns the arc tangent of y/x, using the signs of the two
+// to determine the quadrant of the return value.
+func Atan2(y, x Number) Number {
+	r := x.Real*x.Real + y.Real*y.Real
+	return binary(math.Atan2(y.Real, x.Real), y, x, x.Real/r, -y.Real/r)
+}
+
+// Hypot returns Sqrt(p*p + q*q).
+func Hypot(p, q Number) Number {
+	v := math.Hypot(p.Real, q.Real)
+	return binary(v, p, q, p.Real/v, q.Real/v)
+}
+
+// Log1p returns the natural logarithm of 1 plus x.
+func Log1p(x Number) Number {
+	return unary(math.Log1p(x.Real), x, 1/(1+x.Real))
+}
+
+// Expm1 returns e**x - 1, the base-e exponential of x minus 1.
+func Expm1(x Number) Number {
+	return unary(math.Expm1(x.Real), x, math.Exp(x.Real))
+}
+
+// Log2 returns the binary logarithm of x.
+func Log2(x Number) Number {
+	return unary(math.Log2(x.Real), x, 1/(x.Real*math.Ln2))
+}
+
+// Log10 returns the decimal logarithm of x.
+func Log10(x Number) Number {
+	return unary(math.Log10(x.Real), x, 1/(x.Real*math.Ln10))
+}
+
+// Cbrt returns the cube root of x.
+func Cbrt(x Number) Number {
+	v := math.Cbrt(x.Real)
+	return unary(v, x, 1/(3*v*v))
+}
+
+// Erf returns the error function of x.
+func Erf(x Number) Number {
+	return unary(math.Erf(x.Real), x, 2/math.SqrtPi*math.Exp(-x.Real*x.Real))
+}
+
+// Erfc returns the complementary error function of x.
+func Erfc(x Number) Number {
+	return unary(math.Erfc(x.Real), x, -2/math.SqrtPi*math.Exp(-x.Real*x.Real))
+}
+
+// Mod returns the floating-point remainder of x/y. The quotient x/y,
+// truncated toward zero, is held constant.
+func Mod(x, y Number) Number {
+	return binary(math.Mod(x.Real, y.Real), x, y, 1, -math.Trunc(x.Real/y.Real))
+}
@@ -0,0 +1,170 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package adjoint
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGradient(t *testing.T) {
+	const tol = 1e-14
+	for _, test := range []struct {
+		name string
+		fn   func(x, y Number) Number
+		x, y float64
+		want []float64 // f(x, y), df/dx, df/dy
+	}{
+		{
+			name: "x*y+x",
+			fn:   func(x, y Number) Number { return Add(Mul(x, y), x) },
+			x:    2, y: 3,
+			want: []float64{8, 4, 2},
+		},
+		{
+			name: "x/y-2*y",
+			fn:   func(x, y Number) Number { return Sub(Mul(x, Inv(y)), Scale(2, y)) },
+			x:    3, y: 2,
+			want: []float64{-2.5, 0.5, -0.75 - 2},
+		},
+		{
+			name: "x^y",
+			fn:   Pow,
+			x:    2, y: 3,
+			want: []float64{8, 12, 8 * math.Log(2)},
+		},
+		{
+			name: "x^2",
+			fn:   func(x, y Number) Number { return Pow(x, Number{Real: 2}) },
+			x:    -3, y: 1,
+			want: []float64{9, -6, 0},
+		},
+		{
+			name: "exp(x)*log(y)",
+			fn:   func(x, y Number) Number { return Mul(Exp(x), Log(y)) },
+			x:    0.5, y: 2,
+			want: []float64{math.Exp(0.5) * math.Log(2), math.Exp(0.5) * math.Log(2), math.Exp(0.5) / 2},
+		},
+		{
+			name: "sqrt(abs(x))+sin(y)",
+			fn:   func(x, y Number) Number { return Add(Sqrt(Abs(x)), Sin(y)) },
+			x:    -4, y: 0.5,
+			want: []float64{2 + math.Sin(0.5), -0.25, math.Cos(0.5)},
+		},
+		{
+			name: "cos(x)*tan(y)",
+			fn:   func(x, y Number) Number { return Mul(Cos(x), Tan(y)) },
+			x:    0.5, y: 0,
+			want: []float64{0, 0, math.Cos(0.5)},
+		},
+		{
+			name: "sinh(x)+cosh(y)+tanh(x)",
+			fn:   func(x, y Number) Number { return Add(Add(Sinh(x), Cosh(y)), Tanh(x)) },
+			x:    0, y: 0.5,
+			want: []float64{math.Cosh(0.5), 2, math.Sinh(0.5)},
+		},
+		{
+			name: "asin(x)+acos(y)",
+			fn:   func(x, y Number) Number { return Add(Asin(x), Acos(y)) },
+			x:    0.5, y: 0.5,
+			want: []float64{math.Pi / 2, 1 / math.Sqrt(0.75), -1 / math.Sqrt(0.75)},
+		},
+		{
+			name: "atan(x)+asinh(y)",
+			fn:   func(x, y Number) Number { return Add(Atan(x), Asinh(y)) },
+			x:    1, y: 0,
+			want: []float64{math.Pi / 4, 0.5, 1},
+		},
+		{
+			name: "acosh(x)+atanh(y)",
+			fn:   func(x, y Number) Number { return Add(Acosh(x), Atanh(y)) },
+			x:    2, y: 0.5,
+			want: []float64{math.Acosh(2) + math.Atanh(0.5), 1 / math.Sqrt(3), 1 / 0.75},
+		},
+		{
+			name: "atan2(x,y)",
+			fn:   Atan2,
+			x:    1, y: 2,
+			want: []float64{math.Atan2(1, 2), 0.4, -0.2},
+		},
+		{
+			name: "hypot(x,y)",
+			fn:   Hypot,
+			x:    3, y: 4,
+			want: []float64{5, 0.6, 0.8},
+		},
+		{
+			name: "log1p(x)+expm1(y)",
+			fn:   func(x, y Number) Number { return Add(Log1p(x), Expm1(y)) },
+			x:    1, y: 0.5,
+			want: []float64{math.Log(2) + math.Expm1(0.5), 0.5, math.Exp(0.5)},
+		},
+		{
+			name: "log2(x)+log10(y)",
+			fn:   func(x, y Number) Number { return Add(Log2(x), Log10(y)) },
+			x:    4, y: 100,
+			want: []float64{4, 1 / (4 * math.Ln2), 1 / (100 * math.Ln10)},
+		},
+		{
+			name: "cbrt(x)+erf(y)",
+			fn:   func(x, y Number) Number { return Add(Cbrt(x), Erf(y)) },
+			x:    -8, y: 0,
+			want: []float64{-2, 1.0 / 12, 2 / math.SqrtPi},
+		},
+		{
+			name: "erfc(x)",
+			fn:   func(x, y Number) Number { return Erfc(x) },
+			x:    1, y: 1,
+			want: []float64{math.Erfc(1), -2 / math.SqrtPi * math.Exp(-1), 0},
+		},
+		{
+			name: "mod(x,y)",
+			fn:   Mod,
+			x:    7, y: 2,
+			want: []float64{1, 1, -3},
+		},
+		{
+			name: "constant",
+			fn:   func(x, y Number) Number { return Mul(Number{Real: 2}, Number{Real: 3}) },
+			x:    1, y: 1,
+			want: []float64{6, 0, 0},
+		},
+	} {
+		var tape Tape
+		x, y := tape.Var(test.x), tape.Var(test.y)
+		v := test.fn(x, y)
+		grad := make([]float64, 2)
+		tape.Gradient(grad, v)
+		got := []float64{v.Real, grad[0], grad[1]}
+		for i, want := range test.want {
+			if math.Abs(got[i]-want) > tol*math.Max(1, math.Abs(want)) {
+				t.Errorf("unexpected result for %s: got=%v want=%v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGradientReuse(t *testing.T) {
+	var tape Tape
+	x, y := tape.Var(2), tape.Var(3)
+	u := Mul(x, y)
+	v := Add(u, Sin(x))
+	grad := make([]float64, 2)
+	for _, test := range []struct {
+		y    Number
+		want []float64
+	}{
+		{y: v, want: []float64{3 + math.Cos(2), 2}},
+		{y: u, want: []float64{3, 2}},
+		{y: x, want: []float64{1, 0}},
+		{y: v, want: []float64{3 + math.Cos(2), 2}},
+	} {
+		tape.Gradient(grad, test.y)
+		if grad[0] != test.want[0] || grad[1] != test.want[1] {
+			t.Errorf("unexpected gradient: got=%v want=%v", grad, test.want)
+		}
+	}
+}
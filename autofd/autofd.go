@@ -5,11 +5,15 @@
 package autofd
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
+	"go/constant"
+	"go/printer"
 	"go/token"
 	"go/types"
 	"io"
+	"strconv"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
@@ -18,15 +22,71 @@ import (
 // Func describes which function will be derived.
 type Func struct {
 	Path  string // Import path of the package holding the function.
-	Name  string // Function or method name.
+	Name  string // Function, method or func variable name, with type arguments for generic functions.
 	Deriv string // Name of the output derivative function.
+
+	// Wrt is the name of the float64, float32 or complex128 parameter
+	// with respect to which the function is derived.
+	// Wrt may be empty for functions with a single parameter.
+	// When Wrt is set, the default derivative name is suffixed with "_"+Wrt.
+	Wrt string
+
+	// Mode selects how derivatives are computed.
+	Mode Mode
+
+	// Subgradient selects the derivatives of non-smooth functions,
+	// such as math.Max, at their kinks.
+	Subgradient Subgradient
 }
 
+// Mode describes how derivatives are computed.
+type Mode int
+
+const (
+	// Forward computes derivatives with dual numbers, evaluating the
+	// function once per input for gradients and Jacobians.
+	Forward Mode = iota
+
+	// Reverse computes first derivatives of multivariate functions with
+	// numbers recording their operations on a tape. The function is evaluated
+	// once, and the gradient is obtained by sweeping the tape backward.
+	Reverse
+
+	// Symbolic computes derivatives of functions of a single variable by
+	// applying the rules of differentiation to the expressions of the
+	// function, generating closed-form float64 expressions.
+	Symbolic
+)
+
+// Subgradient describes the derivatives of math.Max and math.Min where
+// their arguments are equal, and the two arguments are differentiable
+// functions whose derivatives may differ.
+type Subgradient int
+
+const (
+	// AverageSubgradient uses the average of the derivatives of the
+	// arguments, e.g. 0.5 for math.Max(x, 0) at 0.
+	AverageSubgradient Subgradient = iota
+
+	// LeftSubgradient uses the derivatives of the argument selected just
+	// before the kink, e.g. 0 for math.Max(x, 0) at 0.
+	LeftSubgradient
+
+	// RightSubgradient uses the derivatives of the argument selected just
+	// after the kink, e.g. 1 for math.Max(x, 0) at 0.
+	RightSubgradient
+
+	// ZeroSubgradient uses zero derivatives.
+	ZeroSubgradient
+)
+
 // Derivative generates code for derivatives from the given function declaration.
-// If d2 is true, the generated function returns both the first and second
-// derivatives. Otherwise, only the first derivative function is generated.
-func Derivative(w io.Writer, f Func, d2 bool) error {
-	gen, err := newGenerator(w, f, d2)
+// The generated function returns all the derivatives up to the provided order,
+// which must be at least 1.
+// The supported functions and the generated code are described in the package
+// documentation.
+func Derivative(w io.Writer, f Func, order int) error {
+	gen, err := newGenerator(w, f, order, make(loader))
 	if err != nil {
 		return fmt.Errorf("could not create derivative generator: %w", err)
 	}
@@ -37,58 +97,79 @@ func Derivative(w io.Writer, f Func, d2 bool) error {
 	return nil
 }
 
-type kind bool
+// form describes the signature of the function being derived.
+type form int
 
 const (
-	d1xKind kind = false
-	d2xKind kind = true
+	f1xForm  form = iota // func(float64) float64, or its float32 and complex128 variants
+	fnxForm              // func([]float64) float64
+	fmnxForm             // func(dst, x []float64)
 )
 
-func (k kind) dpkg() string {
-	switch k {
-	case d1xKind:
-		return "dual"
-	case d2xKind:
-		return "hyperdual"
-	}
-	panic("impossible")
+// helpers tracks the same-package functions called from the function
+// being derived, for which versions operating on dual numbers are generated.
+type helpers struct {
+	seen  map[*types.Func]bool
+	queue []*types.Func
+	targs map[*types.Func]map[*types.TypeParam]types.Type // type arguments of generic functions.
+	vars  map[*types.Var]*types.Func                      // functions standing for func variables.
+	names map[string]bool                                 // names of the versions already generated.
+	rules []string                                        // functions of the math package derived with a rule.
+	kinks []string                                        // math.Max and math.Min, selecting dual numbers.
+
+	derivs map[*types.Func]*types.Func // derivatives declared by rule directives.
 }
 
 type generator struct {
-	w    io.Writer
-	pkg  *packages.Package
-	fct  *types.Func
-	kind kind
-	xvar string
-	der  string
-	err  error
+	w     io.Writer
+	pkg   *packages.Package
+	fct   *types.Func
+	sig   *types.Signature // signature of fct, instantiated for generic functions.
+	order int              // order of the highest derivative.
+	mode  Mode
+	form  form
+
+	subgrad Subgradient // derivatives of math.Max and math.Min at kinks.
+
+	scalar types.Type                      // type of the differentiated values: float64, float32 or complex128.
+	xvar   types.Object                    // seeded variable.
+	targs  map[*types.TypeParam]types.Type // type arguments of generic functions.
+	in     types.Object                    // input variable, which can not be assigned to.
+	der    string
+	err    error
+
+	res     string                // name of the variable holding the result, if any.
+	xd      string                // name of the variable holding the seeded dual number.
+	seeded  bool                  // whether the seeded dual number is used.
+	lifted  map[types.Object]bool // local variables holding dual numbers.
+	idents  map[string]bool       // identifiers of the generated function.
+	temps   map[string]string     // variables holding the subexpressions hoisted out of the current statement.
+	ntemps  int
+	helpers *helpers
+	indent  int
+	header  bool // whether a control clause is being generated.
 }
 
-func newGenerator(w io.Writer, f Func, d2 bool) (*generator, error) {
+func newGenerator(w io.Writer, f Func, order int, pkgs loader) (*generator, error) {
 	path := f.Path
 	name := f.Name
 
-	cfg := &packages.Config{
-		Mode: packages.NeedName |
-			packages.NeedFiles |
-			packages.NeedCompiledGoFiles |
-			packages.NeedSyntax |
-			packages.NeedTypes |
-			packages.NeedTypesInfo,
-	}
-	pkgs, err := packages.Load(cfg, path)
-	if err != nil {
-		return nil, fmt.Errorf("could not load package of %q %s: %w", f.Path, f.Name, err)
+	if order < 1 {
+		return nil, fmt.Errorf("invalid derivative order %d", order)
 	}
 
-	var pkg *packages.Package
-	for _, p := range pkgs {
-		if p.PkgPath == path {
-			pkg = p
-			break
+	var targs []string
+	if i := strings.Index(name, "["); i >= 0 && strings.HasSuffix(name, "]") {
+		for _, targ := range strings.Split(name[i+1:len(name)-1], ",") {
+			targs = append(targs, strings.TrimSpace(targ))
 		}
+		name = name[:i]
 	}
 
+	pkg, err := pkgs.load(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load package of %q %s: %w", f.Path, f.Name, err)
+	}
 	if pkg == nil || len(pkg.Errors) > 0 {
 		return nil, fmt.Errorf("could not find package %q", path)
 	}
@@ -126,27 +207,336 @@ func newGenerator(w io.Writer, f Func, d2 bool) (*generator, error) {
 		if obj == nil {
 			return nil, fmt.Errorf("could not find %s in package %q", name, path)
 		}
-		var ok bool
-		fct, ok = obj.(*types.Func)
-		if !ok {
+		switch obj := obj.(type) {
+		case *types.Func:
+			fct = obj
+		case *types.Var:
+			fct = funcVar(pkg, obj)
+		}
+		if fct == nil {
 			return nil, fmt.Errorf("object %s in package %q is not a func (%T)", name, path, obj)
 		}
 	}
 
-	if !types.Identical(fct.Type(), f1x.Type()) {
+	sig, subst, err := instantiate(pkg.Types, fct, targs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The parameters of the instantiated signature are distinct from
+	// the objects of the function declaration: xvar is taken from the latter.
+	var (
+		form   form
+		xvar   types.Object
+		scalar types.Type = types.Typ[types.Float64]
+		params            = fct.Type().(*types.Signature).Params()
+	)
+	switch {
+	case f.Wrt != "":
+		for i := 0; i < params.Len(); i++ {
+			if params.At(i).Name() == f.Wrt {
+				xvar = params.At(i)
+				scalar = sig.Params().At(i).Type()
+				break
+			}
+		}
+		if xvar == nil {
+			return nil, fmt.Errorf("could not find parameter %s of %s", f.Wrt, name)
+		}
+		if !types.Identical(scalar, types.Typ[types.Float64]) &&
+			!types.Identical(scalar, types.Typ[types.Float32]) &&
+			!types.Identical(scalar, types.Typ[types.Complex128]) ||
+			sig.Results().Len() != 1 || !types.Identical(sig.Results().At(0).Type(), scalar) {
+			return nil, fmt.Errorf("invalid function signature for %s", name)
+		}
+		form = f1xForm
+	case types.Identical(sig, f1x.Type()),
+		types.Identical(sig, f1x32.Type()),
+		types.Identical(sig, c1x.Type()):
+		form = f1xForm
+		xvar = params.At(0)
+		scalar = sig.Params().At(0).Type()
+	case types.Identical(sig, fnx.Type()):
+		form = fnxForm
+		if order > 2 {
+			return nil, fmt.Errorf("derivatives of order %d of %s not supported", order, name)
+		}
+	case types.Identical(sig, fmnx.Type()):
+		form = fmnxForm
+		switch {
+		case order == 2:
+			return nil, fmt.Errorf("second derivatives of %s not supported", name)
+		case order > 2:
+			return nil, fmt.Errorf("derivatives of order %d of %s not supported", order, name)
+		}
+	default:
 		return nil, fmt.Errorf("invalid function signature for %s", name)
 	}
 
+	if types.Identical(scalar, types.Typ[types.Complex128]) && order != 1 {
+		return nil, fmt.Errorf("derivatives of order %d of %s not supported", order, name)
+	}
+
+	if f.Mode == Reverse {
+		switch {
+		case form == f1xForm:
+			return nil, fmt.Errorf("reverse mode derivatives of %s not supported", name)
+		case order != 1:
+			return nil, fmt.Errorf("reverse mode derivatives of order %d not supported", order)
+		}
+	}
+
+	if f.Subgradient < AverageSubgradient || f.Subgradient > ZeroSubgradient {
+		return nil, fmt.Errorf("invalid subgradient %d", f.Subgradient)
+	}
+
+	if f.Mode == Symbolic && (form != f1xForm || !types.Identical(scalar, types.Typ[types.Float64])) {
+		return nil, fmt.Errorf("symbolic derivatives of %s not supported", name)
+	}
+
+	derivs, err := derivRules(pkg)
+	if err != nil {
+		return nil, err
+	}
+
 	der := f.Deriv
 	if der == "" {
-		der = "Deriv" + strings.Replace(f.Name, ".", "_", -1)
+		der = "Deriv" + strings.Replace(name, ".", "_", -1)
+		for _, targ := range targs {
+			der += "_" + targ
+		}
+		if f.Wrt != "" {
+			der += "_" + f.Wrt
+		}
+	}
+
+	return &generator{
+		w:       w,
+		pkg:     pkg,
+		fct:     fct,
+		sig:     sig,
+		order:   order,
+		mode:    f.Mode,
+		form:    form,
+		subgrad: f.Subgradient,
+		scalar:  scalar,
+		xvar:    xvar,
+		targs:   subst,
+		der:     der,
+		lifted:  make(map[types.Object]bool),
+		helpers: &helpers{
+			seen:   make(map[*types.Func]bool),
+			targs:  make(map[*types.Func]map[*types.TypeParam]types.Type),
+			vars:   make(map[*types.Var]*types.Func),
+			names:  make(map[string]bool),
+			derivs: derivs,
+		},
+	}, nil
+}
+
+// funcVar returns a function standing for the provided package-level variable
+// initialized with a function literal, or nil if v is not such a variable.
+// The function has the signature and the parameters of the function literal.
+func funcVar(pkg *packages.Package, v *types.Var) *types.Func {
+	if v.Parent() != pkg.Types.Scope() {
+		return nil
+	}
+	lit := funcLit(pkg, v.Pos())
+	if lit == nil {
+		return nil
+	}
+	sig := pkg.TypesInfo.TypeOf(lit).(*types.Signature)
+	return types.NewFunc(v.Pos(), v.Pkg(), v.Name(), sig)
+}
+
+// funcLit returns the function literal initializing the package-level
+// variable declared at the provided position, or nil.
+func funcLit(pkg *packages.Package, pos token.Pos) *ast.FuncLit {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.ValueSpec)
+				if len(spec.Values) != len(spec.Names) {
+					continue
+				}
+				for i, name := range spec.Names {
+					if name.Pos() != pos {
+						continue
+					}
+					lit, _ := spec.Values[i].(*ast.FuncLit)
+					return lit
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// loadMode is the information loaded for the packages of derived functions.
+const loadMode = packages.NeedName |
+	packages.NeedFiles |
+	packages.NeedCompiledGoFiles |
+	packages.NeedSyntax |
+	packages.NeedTypes |
+	packages.NeedTypesInfo
+
+// loader loads packages with their syntax and type information,
+// caching them by import path.
+type loader map[string]*packages.Package
+
+// load returns the package with the provided import path, or nil if the
+// import path does not match a loaded package.
+func (l loader) load(path string) (*packages.Package, error) {
+	if pkg, ok := l[path]; ok {
+		return pkg, nil
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: loadMode}, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg *packages.Package
+	for _, p := range pkgs {
+		if p.PkgPath == path {
+			pkg = p
+			break
+		}
+	}
+	l[path] = pkg
+	return pkg, nil
+}
+
+// instantiate returns the signature of the provided function instantiated
+// with the named type arguments, and the mapping of its type parameters to
+// those arguments. Type arguments are looked up in the scope of the package,
+// then in the universe scope.
+func instantiate(pkg *types.Package, fct *types.Func, targs []string) (*types.Signature, map[*types.TypeParam]types.Type, error) {
+	sig := fct.Type().(*types.Signature)
+	tparams := sig.TypeParams()
+	switch {
+	case tparams.Len() == 0 && len(targs) == 0:
+		return sig, nil, nil
+	case tparams.Len() == 0:
+		return nil, nil, fmt.Errorf("%s is not a generic function", fct.Name())
+	case tparams.Len() != len(targs):
+		return nil, nil, fmt.Errorf(
+			"got %d type arguments for %s, want %d",
+			len(targs), fct.Name(), tparams.Len(),
+		)
+	}
+
+	args := make([]types.Type, len(targs))
+	for i, targ := range targs {
+		obj := pkg.Scope().Lookup(targ)
+		if obj == nil {
+			obj = types.Universe.Lookup(targ)
+		}
+		tn, ok := obj.(*types.TypeName)
+		if !ok {
+			return nil, nil, fmt.Errorf("could not find type %s", targ)
+		}
+		args[i] = tn.Type()
+	}
+	inst, err := types.Instantiate(nil, sig, args, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not instantiate %s: %w", fct.Name(), err)
 	}
 
-	return &generator{w: w, pkg: pkg, fct: fct, kind: kind(d2), der: der}, nil
+	subst := make(map[*types.TypeParam]types.Type, len(args))
+	for i, arg := range args {
+		subst[tparams.At(i)] = arg
+	}
+	return inst.(*types.Signature), subst, nil
 }
 
 func (g *generator) generate() error {
-	var fct *ast.FuncDecl
+	fct := g.decl(g.fct)
+	args := g.fct.Type().Underlying().(*types.Signature).Params()
+	switch g.form {
+	case f1xForm:
+		err := checkReturns(fct)
+		if err != nil {
+			return err
+		}
+		g.in = g.xvar
+		g.res = g.fresh(fct, "v")
+		g.xd = g.fresh(fct, g.xvar.Name()+"d")
+		g.printf("func %s", g.der)
+		g.params(fct.Type.Params)
+		switch g.order {
+		case 1:
+			g.printf(" %s {\n", g.scalar)
+		default:
+			ds := make([]string, g.order)
+			for i := range ds {
+				ds[i] = fmt.Sprintf("d%d", i+1)
+			}
+			g.printf(" (%s %s) {\n", strings.Join(ds, ", "), g.scalar)
+		}
+		g.indent++
+		switch g.mode {
+		case Symbolic:
+			g.symStmts(fct.Body.List, make(values))
+		default:
+			g.activate(fct.Body)
+			g.seedStmts(fct.Body.List)
+		}
+		g.indent--
+		g.printf("}\n")
+	case fnxForm:
+		err := checkReturns(fct)
+		if err != nil {
+			return err
+		}
+		switch {
+		case g.mode == Reverse:
+			g.adjointGradient(fct, args.At(0))
+		case g.order == 1:
+			g.gradient(fct, args.At(0))
+		case g.order == 2:
+			g.hessian(fct, args.At(0))
+		}
+	case fmnxForm:
+		switch g.mode {
+		case Reverse:
+			g.adjointJacobian(fct, args.At(0), args.At(1))
+		default:
+			g.jacobian(fct, args.At(0), args.At(1))
+		}
+	}
+
+	for i := 0; i < len(g.helpers.queue) && g.err == nil; i++ {
+		fct := g.helpers.queue[i]
+		if g.helpers.names[g.helperName(fct)] {
+			continue
+		}
+		g.helpers.names[g.helperName(fct)] = true
+		g.helper(fct)
+	}
+	for _, name := range g.helpers.rules {
+		if g.err != nil || g.helpers.names[g.ruleName(name)] {
+			continue
+		}
+		g.helpers.names[g.ruleName(name)] = true
+		g.ruleFunc(g.ruleName(name), g.mathName()+"."+name, mathRules[name])
+	}
+	for _, name := range g.helpers.kinks {
+		if g.err != nil || g.helpers.names[g.kinkName(name)] {
+			continue
+		}
+		g.helpers.names[g.kinkName(name)] = true
+		g.kinkFunc(name)
+	}
+
+	return g.err
+}
+
+// decl returns the declaration of the provided function.
+func (g *generator) decl(fct *types.Func) *ast.FuncDecl {
 	for _, f := range g.pkg.Syntax {
 		for i := range f.Decls {
 			decl, ok := f.Decls[i].(*ast.FuncDecl)
@@ -154,185 +544,1802 @@ func (g *generator) generate() error {
 				continue
 			}
 
-			if decl.Name.Name == g.fct.Name() {
-				fct = decl
-				break
+			if g.pkg.TypesInfo.Defs[decl.Name] == fct {
+				return decl
 			}
 		}
 	}
 
-	var (
-		ret     *ast.ReturnStmt
-		returns int
-	)
+	// Functions standing for package-level variables are declared
+	// by the function literal initializing the variable.
+	if lit := funcLit(g.pkg, fct.Pos()); lit != nil {
+		return &ast.FuncDecl{
+			Name: ast.NewIdent(fct.Name()),
+			Type: lit.Type,
+			Body: lit.Body,
+		}
+	}
+	return nil
+}
+
+// checkReturns checks the provided function returns a single value
+// from each of its return statements.
+func checkReturns(fct *ast.FuncDecl) error {
+	var returns []*ast.ReturnStmt
 	ast.Inspect(fct.Body, func(n ast.Node) bool {
-		switch stmt := n.(type) {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
 		case *ast.ReturnStmt:
-			returns++
-			ret = stmt
+			returns = append(returns, n)
 		}
 		return true
 	})
 
-	switch returns {
-	case 0:
+	if len(returns) == 0 {
 		return fmt.Errorf("could not find a return statement")
-	case 1:
-		// ok
-	default:
-		return fmt.Errorf("can not handle functions with multiple return statements")
 	}
 
-	switch len(ret.Results) {
-	case 0:
-		return fmt.Errorf("naked returns not supported")
-	case 1:
-		// ok
-	default:
-		return fmt.Errorf("too many return values")
+	for _, ret := range returns {
+		switch len(ret.Results) {
+		case 0:
+			return fmt.Errorf("naked returns not supported")
+		case 1:
+			// ok
+		default:
+			return fmt.Errorf("too many return values")
+		}
 	}
+	return nil
+}
 
-	args := g.fct.Type().Underlying().(*types.Signature).Params()
-	g.xvar = args.At(0).Name()
-	switch g.kind {
-	case d1xKind:
-		g.printf("func %s(%s float64) float64 {\n", g.der, g.xvar)
-		g.printf("\tv := ")
-		g.expr(ret.Results[0])
-		g.printf("\n\treturn v.Emag\n")
-	case d2xKind:
-		g.printf("func %s(%s float64) (d1, d2 float64) {\n", g.der, g.xvar)
-		g.printf("\tv := ")
-		g.expr(ret.Results[0])
-		g.printf("\n\treturn v.E1mag, v.E1E2mag\n")
-	}
+// gradient generates the gradient of a func([]float64) float64 function.
+// Each component of x is seeded in turn with a dual number.
+func (g *generator) gradient(fct *ast.FuncDecl, x types.Object) {
+	grad := g.fresh(fct, "grad")
+	g.printf("func %s(%s, %s []float64) {\n", g.der, grad, x.Name())
+	g.printf("\tif len(%s) != len(%s) {\n", grad, x.Name())
+	g.printf("\t\tpanic(\"slice length mismatch\")\n")
+	g.printf("\t}\n")
+	fn := g.closure(fct, x)
+	xd, i, v := g.fresh(fct, "xd"), g.fresh(fct, "i"), g.fresh(fct, "v")
+	g.printf("\t%s := make([]%s.Number, len(%s))\n", xd, g.dpkg(), x.Name())
+	g.printf("\tfor %s, %s := range %s {\n", i, v, x.Name())
+	g.printf("\t\t%s[%s].Real = %s\n", xd, i, v)
+	g.printf("\t}\n")
+	g.printf("\tfor %s := range %s {\n", i, xd)
+	g.printf("\t\t%s[%s].Emag = 1\n", xd, i)
+	g.printf("\t\t%s[%s] = %s(%s).Emag\n", grad, i, fn, xd)
+	g.printf("\t\t%s[%s].Emag = 0\n", xd, i)
+	g.printf("\t}\n")
 	g.printf("}\n")
+}
 
-	return g.err
+// hessian generates the Hessian of a func([]float64) float64 function.
+// Each pair of components of x is seeded in turn with a hyperdual number.
+func (g *generator) hessian(fct *ast.FuncDecl, x types.Object) {
+	hess, n := g.fresh(fct, "hess"), g.fresh(fct, "n")
+	g.printf("func %s(%s *mat.SymDense, %s []float64) {\n", g.der, hess, x.Name())
+	g.printf("\tif %s, _ := %s.Dims(); %[1]s != len(%[3]s) {\n", n, hess, x.Name())
+	g.printf("\t\tpanic(\"matrix size mismatch\")\n")
+	g.printf("\t}\n")
+	fn := g.closure(fct, x)
+	xd, i, j, v := g.fresh(fct, "xd"), g.fresh(fct, "i"), g.fresh(fct, "j"), g.fresh(fct, "v")
+	g.printf("\t%s := make([]%s.Number, len(%s))\n", xd, g.dpkg(), x.Name())
+	g.printf("\tfor %s, %s := range %s {\n", i, v, x.Name())
+	g.printf("\t\t%s[%s].Real = %s\n", xd, i, v)
+	g.printf("\t}\n")
+	g.printf("\tfor %s := range %s {\n", i, xd)
+	g.printf("\t\t%s[%s].E1mag = 1\n", xd, i)
+	g.printf("\t\tfor %s := %s; %[1]s < len(%[3]s); %[1]s++ {\n", j, i, xd)
+	g.printf("\t\t\t%s[%s].E2mag = 1\n", xd, j)
+	g.printf("\t\t\t%s.SetSym(%s, %s, %s(%s).E1E2mag)\n", hess, i, j, fn, xd)
+	g.printf("\t\t\t%s[%s].E2mag = 0\n", xd, j)
+	g.printf("\t\t}\n")
+	g.printf("\t\t%s[%s].E1mag = 0\n", xd, i)
+	g.printf("\t}\n")
+	g.printf("}\n")
 }
 
-func (g *generator) expr(expr ast.Expr) {
+// jacobian generates the Jacobian of a func(dst, x []float64) function.
+// Each component of x is seeded in turn with a dual number.
+// As for fd.Jacobian, the number of rows of jac gives the length of dst.
+func (g *generator) jacobian(fct *ast.FuncDecl, dst, x types.Object) {
+	jac, m, n := g.fresh(fct, "jac"), g.fresh(fct, "m"), g.fresh(fct, "n")
+	g.printf("func %s(%s *mat.Dense, %s []float64) {\n", g.der, jac, x.Name())
+	g.printf("\t%s, %s := %s.Dims()\n", m, n, jac)
+	g.printf("\tif %s != len(%s) {\n", n, x.Name())
+	g.printf("\t\tpanic(\"matrix size mismatch\")\n")
+	g.printf("\t}\n")
+	g.lifted[dst] = true
+	g.lifted[x] = true
+	g.activate(fct.Body)
+	g.in = x
+	fn := g.fresh(fct, "fn")
+	g.printf("\t%s := func(%s, %s []%s.Number) {\n", fn, dst.Name(), x.Name(), g.dpkg())
+	g.indent += 2
+	g.stmts(fct.Body.List)
+	g.indent -= 2
+	g.printf("\t}\n")
+	xd, yd := g.fresh(fct, "xd"), g.fresh(fct, "yd")
+	i, j, v := g.fresh(fct, "i"), g.fresh(fct, "j"), g.fresh(fct, "v")
+	g.printf("\t%s := make([]%s.Number, len(%s))\n", xd, g.dpkg(), x.Name())
+	g.printf("\tfor %s, %s := range %s {\n", i, v, x.Name())
+	g.printf("\t\t%s[%s].Real = %s\n", xd, i, v)
+	g.printf("\t}\n")
+	g.printf("\t%s := make([]%s.Number, %s)\n", yd, g.dpkg(), m)
+	g.printf("\tfor %s := range %s {\n", j, xd)
+	g.printf("\t\t%s[%s].Emag = 1\n", xd, j)
+	g.printf("\t\t%s(%s, %s)\n", fn, yd, xd)
+	g.printf("\t\tfor %s, %s := range %s {\n", i, v, yd)
+	g.printf("\t\t\t%s.Set(%s, %s, %s.Emag)\n", jac, i, j, v)
+	g.printf("\t\t}\n")
+	g.printf("\t\t%s[%s].Emag = 0\n", xd, j)
+	g.printf("\t}\n")
+	g.printf("}\n")
+}
+
+// adjointGradient generates the gradient of a func([]float64) float64 function
+// in reverse mode. The function is evaluated once on the variables of a tape,
+// which is then swept backward.
+func (g *generator) adjointGradient(fct *ast.FuncDecl, x types.Object) {
+	grad := g.fresh(fct, "grad")
+	g.printf("func %s(%s, %s []float64) {\n", g.der, grad, x.Name())
+	g.printf("\tif len(%s) != len(%s) {\n", grad, x.Name())
+	g.printf("\t\tpanic(\"slice length mismatch\")\n")
+	g.printf("\t}\n")
+	fn := g.closure(fct, x)
+	tape, xd := g.fresh(fct, "tape"), g.fresh(fct, "xd")
+	i, v := g.fresh(fct, "i"), g.fresh(fct, "v")
+	g.printf("\tvar %s adjoint.Tape\n", tape)
+	g.printf("\t%s := make([]adjoint.Number, len(%s))\n", xd, x.Name())
+	g.printf("\tfor %s, %s := range %s {\n", i, v, x.Name())
+	g.printf("\t\t%s[%s] = %s.Var(%s)\n", xd, i, tape, v)
+	g.printf("\t}\n")
+	g.printf("\t%s.Gradient(%s, %s(%s))\n", tape, grad, fn, xd)
+	g.printf("}\n")
+}
+
+// adjointJacobian generates the Jacobian of a func(dst, x []float64) function
+// in reverse mode. The function is evaluated once on the variables of a tape,
+// which is then swept backward for each row of the Jacobian.
+func (g *generator) adjointJacobian(fct *ast.FuncDecl, dst, x types.Object) {
+	jac, m, n := g.fresh(fct, "jac"), g.fresh(fct, "m"), g.fresh(fct, "n")
+	g.printf("func %s(%s *mat.Dense, %s []float64) {\n", g.der, jac, x.Name())
+	g.printf("\t%s, %s := %s.Dims()\n", m, n, jac)
+	g.printf("\tif %s != len(%s) {\n", n, x.Name())
+	g.printf("\t\tpanic(\"matrix size mismatch\")\n")
+	g.printf("\t}\n")
+	g.lifted[dst] = true
+	g.lifted[x] = true
+	g.activate(fct.Body)
+	g.in = x
+	fn := g.fresh(fct, "fn")
+	g.printf("\t%s := func(%s, %s []adjoint.Number) {\n", fn, dst.Name(), x.Name())
+	g.indent += 2
+	g.stmts(fct.Body.List)
+	g.indent -= 2
+	g.printf("\t}\n")
+	tape, xd, yd, row := g.fresh(fct, "tape"), g.fresh(fct, "xd"), g.fresh(fct, "yd"), g.fresh(fct, "row")
+	i, v := g.fresh(fct, "i"), g.fresh(fct, "v")
+	g.printf("\tvar %s adjoint.Tape\n", tape)
+	g.printf("\t%s := make([]adjoint.Number, len(%s))\n", xd, x.Name())
+	g.printf("\tfor %s, %s := range %s {\n", i, v, x.Name())
+	g.printf("\t\t%s[%s] = %s.Var(%s)\n", xd, i, tape, v)
+	g.printf("\t}\n")
+	g.printf("\t%s := make([]adjoint.Number, %s)\n", yd, m)
+	g.printf("\t%s(%s, %s)\n", fn, yd, xd)
+	g.printf("\t%s := make([]float64, %s)\n", row, n)
+	g.printf("\tfor %s, %s := range %s {\n", i, v, yd)
+	g.printf("\t\t%s.Gradient(%s, %s)\n", tape, row, v)
+	g.printf("\t\t%s.SetRow(%s, %s)\n", jac, i, row)
+	g.printf("\t}\n")
+	g.printf("}\n")
+}
+
+// closure generates a function literal evaluating the provided function
+// on dual numbers, and returns the name of the variable holding it.
+func (g *generator) closure(fct *ast.FuncDecl, x types.Object) string {
+	fn := g.fresh(fct, "fn")
+	g.lifted[x] = true
+	g.activate(fct.Body)
+	g.in = x
+	g.printf("\t%s := func(%s []%[3]s.Number) %[3]s.Number {\n", fn, x.Name(), g.dpkg())
+	g.indent += 2
+	g.stmts(fct.Body.List)
+	g.indent -= 2
+	g.printf("\t}\n")
+	return fn
+}
+
+// fresh returns a name derived from name that does not clash with
+// any identifier appearing in the provided function, nor with the
+// names previously returned by fresh.
+func (g *generator) fresh(fct *ast.FuncDecl, name string) string {
+	if g.idents == nil {
+		g.idents = idents(fct)
+	}
+	v := name
+	for i := 1; g.idents[v]; i++ {
+		v = fmt.Sprintf("%s%d", name, i)
+	}
+	g.idents[v] = true
+	return v
+}
+
+// idents returns the set of identifiers appearing in the provided function.
+func idents(fct *ast.FuncDecl) map[string]bool {
+	used := make(map[string]bool)
+	ast.Inspect(fct, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			used[id.Name] = true
+		}
+		return true
+	})
+	return used
+}
+
+// seedStmts generates code for the body of a function of a single variable.
+// The seeded dual number is defined once, before the statements using it.
+func (g *generator) seedStmts(stmts []ast.Stmt) {
+	w := g.w
+	buf := new(bytes.Buffer)
+	g.w = buf
+	g.stmts(stmts)
+	g.w = w
+	if g.seeded {
+		g.tab()
+		g.printf("%s := ", g.xd)
+		g.seed(g.xvar.Name())
+		g.printf("\n")
+	}
+	_, err := buf.WriteTo(w)
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+func (g *generator) stmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		g.stmt(stmt)
+	}
+}
+
+func (g *generator) stmt(stmt ast.Stmt) {
 	if g.err != nil {
 		return
 	}
+	defer g.forget()
 
-	switch expr := expr.(type) {
+	switch stmt := stmt.(type) {
 	default:
-		g.err = fmt.Errorf("invalid expr type: %#v (%T)", expr, expr)
-	case *ast.BasicLit:
-		g.printf("%s.Number{Real:%s}", g.dpkg(), expr.Value)
-	case *ast.Ident:
-		switch expr.Name {
-		case g.xvar:
-			switch g.kind {
-			case d1xKind:
-				g.printf("%s.Number{Real:%s, Emag:1}", g.dpkg(), expr.Name)
-			case d2xKind:
-				g.printf("%s.Number{Real:%s, E1mag:1, E2mag:1}", g.dpkg(), expr.Name)
+		g.err = fmt.Errorf("invalid statement type: %T", stmt)
+	case *ast.EmptyStmt:
+		// no op
+	case *ast.ReturnStmt:
+		g.hoist(stmt.Results...)
+		if g.res == "" {
+			g.tab()
+			g.printf("return")
+			if len(stmt.Results) > 0 {
+				g.printf(" ")
+				g.expr(stmt.Results[0])
 			}
-		default:
-			g.printf("%s.Number{Real:%s}", g.dpkg(), expr.Name)
+			g.printf("\n")
+			return
 		}
-	case *ast.ParenExpr:
-		g.printf("(")
-		g.expr(expr.X)
-		g.printf(")")
-	case *ast.UnaryExpr:
-		switch expr.Op {
+		g.tab()
+		g.printf("%s := ", g.res)
+		g.expr(stmt.Results[0])
+		g.printf("\n")
+		g.tab()
+		switch g.order {
+		case 1:
+			g.printf("return %s\n", g.result(g.res+".Emag"))
+		case 2:
+			g.printf("return %s, %s\n", g.result(g.res+".E1mag"), g.result(g.res+".E1E2mag"))
 		default:
-			g.err = fmt.Errorf("invalid binary expression token %v", expr.Op)
-		case token.ADD:
-			// no op
-		case token.SUB:
-			g.printf("%[1]s.Mul(%[1]s.Number{Real:-1}, ", g.dpkg())
-			g.expr(expr.X)
-			g.printf(")")
+			g.printf("return ")
+			for i := 1; i <= g.order; i++ {
+				if i > 1 {
+					g.printf(", ")
+				}
+				g.printf("%s", g.result(fmt.Sprintf("%s.Deriv(%d)", g.res, i)))
+			}
+			g.printf("\n")
 		}
-	case *ast.BinaryExpr:
-		switch expr.Op {
+	case *ast.AssignStmt, *ast.IncDecStmt:
+		if stmt, ok := stmt.(*ast.AssignStmt); ok {
+			g.hoist(g.dualRhs(stmt)...)
+		}
+		g.tab()
+		g.simpleStmt(stmt)
+		g.printf("\n")
+	case *ast.DeclStmt:
+		decl, ok := stmt.Decl.(*ast.GenDecl)
+		if !ok {
+			g.err = fmt.Errorf("invalid declaration: %#v (%T)", stmt.Decl, stmt.Decl)
+			return
+		}
+		switch decl.Tok {
+		case token.CONST:
+			g.tab()
+			g.node(decl)
+			g.printf("\n")
+		case token.VAR:
+			for _, spec := range decl.Specs {
+				g.varSpec(spec.(*ast.ValueSpec))
+			}
 		default:
-			g.err = fmt.Errorf("invalid binary expression token %v", expr.Op)
-		case token.ADD:
-			g.printf("%s.Add(", g.dpkg())
-			g.expr(expr.X)
-			g.printf(", ")
-			g.expr(expr.Y)
-			g.printf(")")
-		case token.SUB:
-			g.printf("%s.Sub(", g.dpkg())
-			g.expr(expr.X)
-			g.printf(", ")
-			g.expr(expr.Y)
-			g.printf(")")
-		case token.MUL:
-			g.printf("%s.Mul(", g.dpkg())
-			g.expr(expr.X)
-			g.printf(", ")
-			g.expr(expr.Y)
-			g.printf(")")
-		case token.QUO:
-			g.printf("%s.Mul(", g.dpkg())
-			g.expr(expr.X)
-			g.printf(", ")
-			g.printf("%s.Inv(", g.dpkg())
-			g.expr(expr.Y)
-			g.printf("))")
+			g.err = fmt.Errorf("invalid declaration token %v", decl.Tok)
 		}
-
-	case *ast.CallExpr:
-		g.expr(expr.Fun)
-		g.printf("(")
-		for i, arg := range expr.Args {
-			if i > 0 {
+	case *ast.BlockStmt:
+		g.tab()
+		g.block(stmt)
+		g.printf("\n")
+	case *ast.IfStmt:
+		g.tab()
+		g.ifStmt(stmt)
+		g.printf("\n")
+	case *ast.SwitchStmt:
+		g.tab()
+		g.printf("switch ")
+		if stmt.Init != nil {
+			g.header = true
+			g.simpleStmt(stmt.Init)
+			g.header = false
+			g.printf("; ")
+		}
+		if stmt.Tag != nil {
+			g.real(stmt.Tag)
+			g.printf(" ")
+		}
+		g.printf("{\n")
+		for _, cc := range stmt.Body.List {
+			g.caseClause(cc.(*ast.CaseClause))
+		}
+		g.tab()
+		g.printf("}\n")
+	case *ast.ForStmt:
+		g.tab()
+		g.printf("for ")
+		if stmt.Init != nil || stmt.Post != nil {
+			g.header = true
+			if stmt.Init != nil {
+				g.simpleStmt(stmt.Init)
+			}
+			g.printf("; ")
+			if stmt.Cond != nil {
+				g.real(stmt.Cond)
+			}
+			g.printf("; ")
+			if stmt.Post != nil {
+				g.simpleStmt(stmt.Post)
+			}
+			g.header = false
+			g.printf(" ")
+		} else if stmt.Cond != nil {
+			g.real(stmt.Cond)
+			g.printf(" ")
+		}
+		g.block(stmt.Body)
+		g.printf("\n")
+	case *ast.RangeStmt:
+		dual := g.isDualSlice(stmt.X)
+		if stmt.Value != nil && dual && !g.assignable(stmt.Value, stmt.X) {
+			return
+		}
+		g.tab()
+		g.printf("for ")
+		if stmt.Key != nil {
+			g.lhs(stmt.Key)
+			if stmt.Value != nil {
 				g.printf(", ")
+				g.lhs(stmt.Value)
 			}
-			g.expr(arg)
+			g.printf(" %s ", stmt.Tok)
 		}
-		g.printf(")")
-
-	case *ast.SelectorExpr:
-		x, ok := expr.X.(*ast.Ident)
-		if !ok || x.Name != "math" {
-			g.err = fmt.Errorf("invalid selector expression %#v", expr)
-		}
-		switch expr.Sel.Name {
-		case "Abs",
-			"Acos", "Acosh",
-			"Asin", "Asinh",
-			"Atan", "Atanh",
-			"Cos", "Cosh",
-			"Exp", "Log",
-			"Pow",
-			"Sin", "Sinh",
-			"Sqrt",
-			"Tan", "Tanh":
-			g.printf("%s.%s", g.dpkg(), expr.Sel.Name)
-		case "E", "Pi", "Phi",
-			"Sqrt2", "SqrtE", "SqrtPi", "SqrtPhi",
-			"Ln2", "Log2E", "Ln10", "Log10E":
-			g.printf("%s.Number{Real: math.%s}", g.dpkg(), expr.Sel.Name)
-		default:
-			g.err = fmt.Errorf("invalid selector expression %#v", expr)
+		g.printf("range ")
+		if dual {
+			g.expr(stmt.X)
+		} else {
+			g.real(stmt.X)
 		}
+		g.printf(" ")
+		g.block(stmt.Body)
+		g.printf("\n")
+	case *ast.LabeledStmt:
+		g.printf("%s:\n", stmt.Label.Name)
+		g.stmt(stmt.Stmt)
+	case *ast.BranchStmt:
+		g.tab()
+		g.node(stmt)
+		g.printf("\n")
 	}
 }
 
-func (g *generator) printf(format string, args ...interface{}) {
-	fmt.Fprintf(g.w, format, args...)
+// simpleStmt generates code for statements that may appear in the
+// initialization part of control flow statements.
+func (g *generator) simpleStmt(stmt ast.Stmt) {
+	if g.err != nil {
+		return
+	}
+
+	switch stmt := stmt.(type) {
+	default:
+		g.err = fmt.Errorf("invalid statement type: %T", stmt)
+	case *ast.AssignStmt:
+		g.assign(stmt)
+	case *ast.IncDecStmt:
+		if !g.isLifted(stmt.X) {
+			g.lhs(stmt.X)
+			g.printf("%s", stmt.Tok)
+			return
+		}
+		op := token.ADD
+		if stmt.Tok == token.DEC {
+			op = token.SUB
+		}
+		g.update(stmt.X, op, &ast.BasicLit{Kind: token.INT, Value: "1"})
+	}
 }
 
-func (g *generator) dpkg() string {
-	return g.kind.dpkg()
+// block generates code for the provided block, without a trailing newline.
+func (g *generator) block(blk *ast.BlockStmt) {
+	g.printf("{\n")
+	g.indent++
+	g.stmts(blk.List)
+	g.indent--
+	g.tab()
+	g.printf("}")
 }
 
-// f1x is the pre-computed signature of 'func(float64) float64'.
-// This will be checked against to make sure Derivative is called on valid functions.
-var f1x *types.Func
+// ifStmt generates code for the provided if statement.
+// Conditions are evaluated on the real part of dual numbers.
+func (g *generator) ifStmt(stmt *ast.IfStmt) {
+	g.printf("if ")
+	if stmt.Init != nil {
+		g.header = true
+		g.simpleStmt(stmt.Init)
+		g.header = false
+		g.printf("; ")
+	}
+	g.real(stmt.Cond)
+	g.printf(" ")
+	g.block(stmt.Body)
+	switch els := stmt.Else.(type) {
+	case nil:
+		// no op
+	case *ast.IfStmt:
+		g.printf(" else ")
+		g.ifStmt(els)
+	case *ast.BlockStmt:
+		g.printf(" else ")
+		g.block(els)
+	}
+}
 
-func init() {
-	const variadic = false
+// caseClause generates code for the provided case clause of a switch statement.
+func (g *generator) caseClause(cc *ast.CaseClause) {
+	g.tab()
+	switch cc.List {
+	case nil:
+		g.printf("default:\n")
+	default:
+		g.printf("case ")
+		for i, expr := range cc.List {
+			if i > 0 {
+				g.printf(", ")
+			}
+			g.real(expr)
+		}
+		g.printf(":\n")
+	}
+	g.indent++
+	g.stmts(cc.Body)
+	g.indent--
+}
+
+// assign generates code for the provided assignment statement.
+// Assignments to variables holding dual numbers are lifted to assignments of
+// dual numbers.
+func (g *generator) assign(stmt *ast.AssignStmt) {
+	// math.Lgamma also returns the sign of its value.
+	lgamma := len(stmt.Lhs) == 2 && len(stmt.Rhs) == 1 && g.isRule(stmt.Rhs[0])
+	if len(stmt.Lhs) != len(stmt.Rhs) && !lgamma {
+		g.err = fmt.Errorf("can not handle multi-valued assignments")
+		return
+	}
+
+	for _, lhs := range stmt.Lhs {
+		id, ok := lhs.(*ast.Ident)
+		if !ok {
+			if idx, ok := lhs.(*ast.IndexExpr); ok && g.isInput(idx.X) || g.isFloat(lhs) && !g.isLifted(lhs) {
+				g.err = fmt.Errorf("can not assign to %s", types.ExprString(lhs))
+				return
+			}
+			continue
+		}
+		obj := g.object(id)
+		switch {
+		case obj == nil:
+			// blank identifier.
+		case obj == g.in:
+			g.err = fmt.Errorf("can not assign to %s", id.Name)
+			return
+		case obj.Parent() == g.pkg.Types.Scope():
+			g.err = fmt.Errorf("can not assign to package-level variable %s", id.Name)
+			return
+		}
+	}
+
+	switch stmt.Tok {
+	case token.DEFINE, token.ASSIGN:
+		for i, lhs := range stmt.Lhs {
+			if i > 0 {
+				g.printf(", ")
+			}
+			g.lhs(lhs)
+		}
+		g.printf(" %s ", stmt.Tok)
+		for i, rhs := range stmt.Rhs {
+			if i > 0 {
+				g.printf(", ")
+			}
+			switch {
+			case !g.assignable(stmt.Lhs[i], rhs):
+				return
+			case g.isLifted(stmt.Lhs[i]):
+				g.expr(rhs)
+			default:
+				g.real(rhs)
+			}
+		}
+
+	case token.ADD_ASSIGN, token.SUB_ASSIGN, token.MUL_ASSIGN, token.QUO_ASSIGN:
+		if !g.isLifted(stmt.Lhs[0]) {
+			if !g.assignable(stmt.Lhs[0], stmt.Rhs[0]) {
+				return
+			}
+			g.lhs(stmt.Lhs[0])
+			g.printf(" %s ", stmt.Tok)
+			g.real(stmt.Rhs[0])
+			return
+		}
+		g.update(stmt.Lhs[0], assignOps[stmt.Tok], stmt.Rhs[0])
+
+	default:
+		if g.isLifted(stmt.Lhs[0]) {
+			g.err = fmt.Errorf("invalid assignment token %v", stmt.Tok)
+			return
+		}
+		if !g.assignable(stmt.Lhs[0], stmt.Rhs[0]) {
+			return
+		}
+		g.lhs(stmt.Lhs[0])
+		g.printf(" %s ", stmt.Tok)
+		g.real(stmt.Rhs[0])
+	}
+}
+
+// assignOps maps arithmetic assignment tokens to their binary operators.
+var assignOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN: token.ADD,
+	token.SUB_ASSIGN: token.SUB,
+	token.MUL_ASSIGN: token.MUL,
+	token.QUO_ASSIGN: token.QUO,
+}
+
+// assignable returns whether the value of rhs can be assigned to lhs, and
+// sets the error of the generator if not. Values depending on the seeded
+// variable can only be assigned to variables holding dual numbers, and to
+// integer and boolean variables, whose values are piecewise constant.
+func (g *generator) assignable(lhs, rhs ast.Expr) bool {
+	if id, ok := lhs.(*ast.Ident); ok && id.Name == "_" {
+		return true
+	}
+	if g.isLifted(lhs) || g.isDiscrete(lhs) || !g.depends(rhs) && !g.isLifted(rhs) {
+		return true
+	}
+	g.err = fmt.Errorf("can not assign %s to %s of type %s",
+		types.ExprString(rhs), types.ExprString(lhs), g.typeString(g.typ(g.pkg.TypesInfo.TypeOf(lhs))),
+	)
+	return false
+}
+
+// update generates code for 'lhs = lhs op rhs' where lhs holds a dual number.
+func (g *generator) update(lhs ast.Expr, op token.Token, rhs ast.Expr) {
+	if !g.isLifted(lhs) {
+		g.err = fmt.Errorf("can not assign to %s", types.ExprString(lhs))
+		return
+	}
+	g.lhs(lhs)
+	g.printf(" = ")
+	g.expr(&ast.BinaryExpr{X: lhs, Op: op, Y: rhs})
+}
+
+// varSpec generates code for a var declaration.
+// Declarations of variables holding dual numbers are lifted to declarations
+// of dual numbers.
+func (g *generator) varSpec(spec *ast.ValueSpec) {
+	if len(spec.Values) != 0 && len(spec.Values) != len(spec.Names) {
+		g.err = fmt.Errorf("can not handle multi-valued declarations")
+		return
+	}
+
+	lift := false
+	for _, name := range spec.Names {
+		if g.lifted[g.object(name)] {
+			lift = true
+		}
+	}
+	if !lift {
+		for i, v := range spec.Values {
+			if !g.assignable(spec.Names[i], v) {
+				return
+			}
+		}
+		g.tab()
+		g.printf("var ")
+		g.node(spec)
+		g.printf("\n")
+		return
+	}
+
+	g.hoist(spec.Values...)
+	g.tab()
+	g.printf("var ")
+	for i, name := range spec.Names {
+		if i > 0 {
+			g.printf(", ")
+		}
+		g.lifted[g.object(name)] = true
+		g.printf("%s", name.Name)
+	}
+	switch {
+	case len(spec.Values) != 0:
+		g.printf(" = ")
+		for i, v := range spec.Values {
+			if i > 0 {
+				g.printf(", ")
+			}
+			g.expr(v)
+		}
+	case g.order <= 2:
+		g.printf(" %s.Number", g.dpkg())
+	default:
+		// Taylor series are slices: their zero value has no real part.
+		g.printf(" = ")
+		for i := range spec.Names {
+			if i > 0 {
+				g.printf(", ")
+			}
+			g.number("0")
+		}
+	}
+	g.printf("\n")
+}
+
+// lhs generates code for the left-hand side of an assignment.
+func (g *generator) lhs(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		g.printf("%s", expr.Name)
+	case *ast.IndexExpr:
+		if !g.isLifted(expr.X) {
+			g.real(expr)
+			return
+		}
+		g.real(expr.X)
+		g.printf("[")
+		g.real(expr.Index)
+		g.printf("]")
+	default:
+		g.real(expr)
+	}
+}
+
+// real generates code for expressions evaluated on the real part of dual numbers.
+func (g *generator) real(expr ast.Expr) {
+	if g.err != nil {
+		return
+	}
+	if lit := g.folded(expr); lit != nil {
+		g.node(lit)
+		return
+	}
+
+	switch expr := expr.(type) {
+	default:
+		g.node(expr)
+	case *ast.Ident:
+		switch {
+		case g.typeArg(expr) != nil:
+			g.printf("%s", g.typeString(g.typeArg(expr)))
+		case g.isLifted(expr) && g.isFloat32(expr):
+			g.printf("float32(%s%s)", expr.Name, g.realPart())
+		case g.isLifted(expr) && g.isFloat(expr):
+			g.printf("%s%s", expr.Name, g.realPart())
+		default:
+			g.printf("%s", expr.Name)
+		}
+	case *ast.ParenExpr:
+		g.printf("(")
+		g.real(expr.X)
+		g.printf(")")
+	case *ast.UnaryExpr:
+		g.printf("%s", expr.Op)
+		g.real(expr.X)
+	case *ast.BinaryExpr:
+		g.real(expr.X)
+		g.printf(" %s ", expr.Op)
+		g.real(expr.Y)
+	case *ast.CallExpr:
+		g.real(expr.Fun)
+		g.printf("(")
+		for i, arg := range expr.Args {
+			if i > 0 {
+				g.printf(", ")
+			}
+			g.real(arg)
+		}
+		g.printf(")")
+	case *ast.SelectorExpr:
+		g.real(expr.X)
+		g.printf(".%s", expr.Sel.Name)
+	case *ast.IndexExpr:
+		g.real(expr.X)
+		g.printf("[")
+		g.real(expr.Index)
+		g.printf("]")
+		if g.isLifted(expr.X) {
+			g.printf("%s", g.realPart())
+		}
+	}
+}
+
+func (g *generator) expr(expr ast.Expr) {
+	if g.err != nil {
+		return
+	}
+
+	// Non-differentiated values are evaluated as plain numbers,
+	// and only then turned into a dual number.
+	if g.isFloat(expr) && g.isConstant(expr) {
+		g.constant(expr)
+		return
+	}
+	if v, ok := g.temp(expr); ok {
+		g.printf("%s", v)
+		return
+	}
+
+	switch expr := expr.(type) {
+	default:
+		g.err = fmt.Errorf("invalid expr type: %#v (%T)", expr, expr)
+	case *ast.BasicLit:
+		g.number(expr.Value)
+	case *ast.Ident:
+		obj := g.object(expr)
+		switch {
+		case obj == g.xvar:
+			g.seeded = true
+			g.printf("%s", g.xd)
+		case g.lifted[obj]:
+			g.printf("%s", expr.Name)
+		default:
+			g.constant(expr)
+		}
+	case *ast.ParenExpr:
+		g.printf("(")
+		g.expr(expr.X)
+		g.printf(")")
+	case *ast.UnaryExpr:
+		switch expr.Op {
+		default:
+			g.err = fmt.Errorf("invalid binary expression token %v", expr.Op)
+		case token.ADD:
+			// no op
+		case token.SUB:
+			g.printf("%s.Scale(-1, ", g.dpkg())
+			g.expr(expr.X)
+			g.printf(")")
+		}
+	case *ast.BinaryExpr:
+		g.binary(expr)
+	case *ast.CompositeLit:
+		g.compositeLit(expr)
+
+	case *ast.IndexExpr:
+		switch {
+		case g.isLifted(expr.X):
+			g.real(expr.X)
+			g.printf("[")
+			g.real(expr.Index)
+			g.printf("]")
+		case g.depends(expr):
+			g.err = fmt.Errorf("invalid index expression %s", types.ExprString(expr))
+		default:
+			g.constant(expr)
+		}
+	case *ast.CallExpr:
+		if g.pkg.TypesInfo.Types[expr.Fun].IsType() {
+			g.conv(expr)
+			return
+		}
+		if g.isBuiltin(expr) {
+			if g.depends(expr) {
+				g.err = fmt.Errorf("invalid call to builtin %s", types.ExprString(expr))
+				return
+			}
+			// Builtins, such as complex, are only called on
+			// non-differentiated values.
+			g.constant(expr)
+			return
+		}
+		if fct := g.callee(expr); fct != nil {
+			g.call(fct, expr)
+			return
+		}
+		if g.isRule(expr) {
+			g.rule(expr)
+			return
+		}
+		if g.isStep(expr) {
+			g.constant(expr)
+			return
+		}
+		if g.isKink(expr) {
+			g.kink(expr)
+			return
+		}
+		if g.isPowReal(expr) {
+			g.printf("%s.PowReal(", g.dpkg())
+			g.expr(expr.Args[0])
+			g.printf(", ")
+			g.factor(expr.Args[1])
+			g.printf(")")
+			return
+		}
+		g.expr(expr.Fun)
+		g.printf("(")
+		for i, arg := range expr.Args {
+			if i > 0 {
+				g.printf(", ")
+			}
+			g.expr(arg)
+		}
+		g.printf(")")
+
+	case *ast.SelectorExpr:
+		// Constants and variables of any package are non-differentiated
+		// values, handled above. Only functions of the math package, or
+		// of the math/cmplx package for complex functions, are mapped.
+		switch {
+		case g.importPath(expr.X) == g.mathPath() && g.isMathFunc(expr.Sel.Name):
+			g.printf("%s.%s", g.dpkg(), expr.Sel.Name)
+		default:
+			g.err = fmt.Errorf("invalid selector expression %s", types.ExprString(expr))
+		}
+	}
+}
+
+// compositeLit generates a slice or an array of dual numbers from the provided
+// literal of float64 values.
+func (g *generator) compositeLit(lit *ast.CompositeLit) {
+	switch typ := g.typ(g.pkg.TypesInfo.TypeOf(lit)).Underlying().(type) {
+	case *types.Slice:
+		g.printf("[]%s.Number{", g.dpkg())
+	case *types.Array:
+		g.printf("[%d]%s.Number{", typ.Len(), g.dpkg())
+	}
+	if !g.isDualSlice(lit) {
+		g.err = fmt.Errorf("invalid composite literal %s", types.ExprString(lit))
+		return
+	}
+	for i, elt := range lit.Elts {
+		if i > 0 {
+			g.printf(", ")
+		}
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			g.real(kv.Key)
+			g.printf(": ")
+			elt = kv.Value
+		}
+		g.expr(elt)
+	}
+	g.printf("}")
+}
+
+// binary generates code for a binary expression on dual numbers.
+// Products and quotients by non-differentiated values are scalings, and
+// additions of zero and products by one are elided.
+func (g *generator) binary(expr *ast.BinaryExpr) {
+	x, y := expr.X, expr.Y
+	cx, cy := !g.depends(x), !g.depends(y)
+	switch expr.Op {
+	default:
+		g.err = fmt.Errorf("invalid binary expression token %v", expr.Op)
+	case token.ADD, token.SUB:
+		switch {
+		case g.isConst(y, 0):
+			g.expr(x)
+		case g.isConst(x, 0) && expr.Op == token.ADD:
+			g.expr(y)
+		case g.isConst(x, 0):
+			g.printf("%s.Scale(-1, ", g.dpkg())
+			g.expr(y)
+			g.printf(")")
+		default:
+			fct := "Add"
+			if expr.Op == token.SUB {
+				fct = "Sub"
+			}
+			g.printf("%s.%s(", g.dpkg(), fct)
+			g.expr(x)
+			g.printf(", ")
+			g.expr(y)
+			g.printf(")")
+		}
+	case token.MUL:
+		switch {
+		case g.isConst(x, 1):
+			g.expr(y)
+		case g.isConst(y, 1):
+			g.expr(x)
+		case cx:
+			g.scale(x, y)
+		case cy:
+			g.scale(y, x)
+		default:
+			g.printf("%s.Mul(", g.dpkg())
+			g.expr(x)
+			g.printf(", ")
+			g.expr(y)
+			g.printf(")")
+		}
+	case token.QUO:
+		switch {
+		case g.isConst(y, 1):
+			g.expr(x)
+		case g.isConst(x, 1):
+			g.printf("%s.Inv(", g.dpkg())
+			g.expr(y)
+			g.printf(")")
+		case cy:
+			g.printf("%s.Scale(1.0/", g.dpkg())
+			g.factor(y)
+			g.printf(", ")
+			g.expr(x)
+			g.printf(")")
+		case cx:
+			g.printf("%s.Scale(", g.dpkg())
+			g.factor(x)
+			g.printf(", %s.Inv(", g.dpkg())
+			g.expr(y)
+			g.printf("))")
+		default:
+			g.printf("%s.Mul(", g.dpkg())
+			g.expr(x)
+			g.printf(", %s.Inv(", g.dpkg())
+			g.expr(y)
+			g.printf("))")
+		}
+	}
+}
+
+// scale generates the product of the dual number x by the
+// non-differentiated value f.
+func (g *generator) scale(f, x ast.Expr) {
+	g.printf("%s.Scale(", g.dpkg())
+	g.factor(f)
+	g.printf(", ")
+	g.expr(x)
+	g.printf(")")
+}
+
+// factor prints the provided non-differentiated expression as a plain number
+// of the type of the real part of dual numbers.
+func (g *generator) factor(expr ast.Expr) {
+	if _, ok := expr.(*ast.BasicLit); !ok && g.isFloat32(expr) {
+		g.printf("float64(")
+		g.real(expr)
+		g.printf(")")
+		return
+	}
+	g.real(expr)
+}
+
+// isConst returns whether the provided expression is the constant v.
+func (g *generator) isConst(expr ast.Expr, v int64) bool {
+	val := g.pkg.TypesInfo.Types[expr].Value
+	return val != nil && constant.Compare(val, token.EQL, constant.MakeInt64(v))
+}
+
+// constValue returns the exact value of the provided constant expression of
+// int or float64 type, as computed by the type checker, or nil if the
+// expression is not such a constant. Constants of other types are not
+// reported, as literals of their value would not have their type.
+// Values of float64 type are returned as float values.
+func (g *generator) constValue(expr ast.Expr) constant.Value {
+	tv := g.pkg.TypesInfo.Types[expr]
+	if tv.Value == nil {
+		return nil
+	}
+	basic, ok := tv.Type.(*types.Basic)
+	if !ok {
+		return nil
+	}
+	switch basic.Kind() {
+	case types.Int, types.UntypedInt:
+		return tv.Value
+	case types.Float64, types.UntypedFloat:
+		return constant.ToFloat(tv.Value)
+	}
+	return nil
+}
+
+// folded returns the literal of the value of the provided constant expression,
+// as in 1.5707963267948966 for math.Pi / 2, or nil if the expression is not
+// a constant of int or float64 type.
+// Literals, possibly negated, and named constants are kept as written.
+func (g *generator) folded(expr ast.Expr) ast.Expr {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+		if _, ok := u.X.(*ast.BasicLit); ok {
+			return nil
+		}
+	}
+	switch expr.(type) {
+	case *ast.BasicLit, *ast.Ident, *ast.SelectorExpr:
+		return nil
+	}
+	v := g.constValue(expr)
+	switch {
+	case v == nil:
+		return nil
+	case v.Kind() == constant.Float:
+		// Floating-point literals keep the type of untyped declarations.
+		return floatLit(v)
+	}
+	return numLit(v)
+}
+
+// isMathFunc returns whether the named function of the math package,
+// or of the math/cmplx package for complex functions, has a dual number
+// equivalent.
+func (g *generator) isMathFunc(name string) bool {
+	switch name {
+	case "Abs":
+		// cmplx.Abs is not holomorphic.
+		return !g.isComplex()
+	case "Acos", "Acosh",
+		"Asin", "Asinh",
+		"Atan", "Atanh",
+		"Cos", "Cosh",
+		"Exp", "Log",
+		"Pow",
+		"Sin", "Sinh",
+		"Sqrt",
+		"Tan", "Tanh":
+		return true
+	}
+	return false
+}
+
+// mathFunc returns the name of the function of the math package called by
+// the provided expression, if any. Functions of the math/cmplx package
+// are not reported for complex functions.
+func (g *generator) mathFunc(expr ast.Expr) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || g.isComplex() || g.importPath(sel.X) != "math" {
+		return "", false
+	}
+	return sel.Sel.Name, true
+}
+
+// isPowReal returns whether the provided expression raises a dual number
+// to a non-differentiated power.
+func (g *generator) isPowReal(expr *ast.CallExpr) bool {
+	name, _ := g.mathFunc(expr)
+	return name == "Pow" && !g.depends(expr.Args[1])
+}
+
+// isBuiltin returns whether the provided expression calls a builtin function.
+func (g *generator) isBuiltin(expr *ast.CallExpr) bool {
+	id, ok := expr.Fun.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	_, ok = g.object(id).(*types.Builtin)
+	return ok
+}
+
+// callee returns the same-package function called by the provided
+// expression, or nil. Package-level variables initialized with function
+// literals are followed as functions.
+// Generic functions may be explicitly instantiated.
+func (g *generator) callee(expr *ast.CallExpr) *types.Func {
+	fun := expr.Fun
+	if idx, ok := fun.(*ast.IndexExpr); ok {
+		fun = idx.X
+	}
+	id, ok := fun.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	switch obj := g.object(id).(type) {
+	case *types.Func:
+		if obj.Pkg() == g.pkg.Types {
+			return obj
+		}
+	case *types.Var:
+		fct, ok := g.helpers.vars[obj]
+		if !ok {
+			fct = funcVar(g.pkg, obj)
+			g.helpers.vars[obj] = fct
+		}
+		return fct
+	}
+	return nil
+}
+
+// call generates a call to the version operating on dual numbers
+// of the provided same-package function.
+func (g *generator) call(fct *types.Func, expr *ast.CallExpr) {
+	if !g.helpers.seen[fct] {
+		g.helpers.seen[fct] = true
+		g.helpers.queue = append(g.helpers.queue, fct)
+		g.helpers.targs[fct] = g.infer(fct, expr)
+	}
+	targs := g.helpers.targs[fct]
+	params := fct.Type().(*types.Signature).Params()
+	g.printf("%s(", g.helperName(fct))
+	for i, arg := range expr.Args {
+		if i > 0 {
+			g.printf(", ")
+		}
+		switch {
+		case g.isFloatType(subst(targs, params.At(i).Type())):
+			g.expr(arg)
+		case !g.isDiscrete(arg) && (g.depends(arg) || g.isLifted(arg)):
+			g.err = fmt.Errorf("can not pass %s as parameter %s of %s", types.ExprString(arg), params.At(i).Name(), fct.Name())
+		default:
+			g.real(arg)
+		}
+	}
+	g.printf(")")
+}
+
+// infer returns the type arguments of the provided call to a generic function,
+// inferred from the arguments passed as parameters of type parameter types.
+func (g *generator) infer(fct *types.Func, expr *ast.CallExpr) map[*types.TypeParam]types.Type {
+	sig := fct.Type().(*types.Signature)
+	tparams := sig.TypeParams()
+	if tparams.Len() == 0 {
+		return nil
+	}
+	targs := make(map[*types.TypeParam]types.Type, tparams.Len())
+	if idx, ok := expr.Fun.(*ast.IndexExpr); ok {
+		targs[tparams.At(0)] = g.typ(g.pkg.TypesInfo.TypeOf(idx.Index))
+	}
+	for i, arg := range expr.Args {
+		tp, ok := sig.Params().At(i).Type().(*types.TypeParam)
+		if !ok || targs[tp] != nil {
+			continue
+		}
+		targs[tp] = types.Default(g.typ(g.pkg.TypesInfo.TypeOf(arg)))
+	}
+	return targs
+}
+
+// helperName returns the name of the version operating on dual numbers
+// of the provided function.
+func (g *generator) helperName(fct *types.Func) string {
+	return g.dpkg() + "_" + fct.Name()
+}
+
+// helper generates the version operating on dual numbers of the provided
+// same-package function.
+// Its float64 parameters and result are replaced by dual numbers.
+func (g *generator) helper(fct *types.Func) {
+	if _, ok := g.helpers.derivs[fct]; ok {
+		g.derivRule(fct)
+		return
+	}
+
+	h := &generator{
+		w:       g.w,
+		pkg:     g.pkg,
+		fct:     fct,
+		order:   g.order,
+		mode:    g.mode,
+		scalar:  g.scalar,
+		form:    g.form,
+		targs:   g.helpers.targs[fct],
+		lifted:  make(map[types.Object]bool),
+		helpers: g.helpers,
+	}
+
+	decl := g.decl(fct)
+	h.idents = idents(decl)
+	sig := fct.Type().(*types.Signature)
+	if sig.Variadic() || sig.Results().Len() != 1 ||
+		!h.isFloatType(sig.Results().At(0).Type()) {
+		g.err = fmt.Errorf("invalid function signature for %s", fct.Name())
+		return
+	}
+	err := checkReturns(decl)
+	if err != nil {
+		g.err = fmt.Errorf("could not generate %s: %w", g.helperName(fct), err)
+		return
+	}
+
+	g.printf("\nfunc %s(", h.helperName(fct))
+	for i, field := range decl.Type.Params.List {
+		if i > 0 {
+			g.printf(", ")
+		}
+		for j, name := range field.Names {
+			if j > 0 {
+				g.printf(", ")
+			}
+			g.printf("%s", name.Name)
+		}
+		if len(field.Names) > 0 {
+			g.printf(" ")
+		}
+		switch {
+		case h.isFloatType(g.pkg.TypesInfo.TypeOf(field.Type)):
+			for _, name := range field.Names {
+				h.lifted[g.object(name)] = true
+			}
+			g.printf("%s.Number", g.dpkg())
+		default:
+			h.typeExpr(field.Type)
+		}
+	}
+	g.printf(") %s.Number {\n", g.dpkg())
+	h.activate(decl.Body)
+	h.indent++
+	h.stmts(decl.Body.List)
+	g.printf("}\n")
+	if h.err != nil {
+		g.err = fmt.Errorf("could not generate %s: %w", h.helperName(fct), h.err)
+	}
+}
+
+// conv generates code for a type conversion.
+func (g *generator) conv(expr *ast.CallExpr) {
+	if !g.isFloat(expr) {
+		g.err = fmt.Errorf("invalid conversion %s", types.ExprString(expr))
+		return
+	}
+	arg := expr.Args[0]
+	switch {
+	case g.isFloat(arg):
+		g.expr(arg)
+	case g.depends(arg):
+		g.err = fmt.Errorf("invalid conversion %s", types.ExprString(expr))
+	default:
+		// Conversions from integers are piecewise constant.
+		g.constant(expr)
+	}
+}
+
+// constant generates a dual number literal from the provided
+// non-differentiated expression.
+func (g *generator) constant(expr ast.Expr) {
+	g.open()
+	g.factor(expr)
+	g.close()
+}
+
+// params prints the provided parameter list verbatim, with type parameters
+// replaced by their type arguments.
+func (g *generator) params(params *ast.FieldList) {
+	g.printf("(")
+	for i, field := range params.List {
+		if i > 0 {
+			g.printf(", ")
+		}
+		for j, name := range field.Names {
+			if j > 0 {
+				g.printf(", ")
+			}
+			g.printf("%s", name.Name)
+		}
+		if len(field.Names) > 0 {
+			g.printf(" ")
+		}
+		g.typeExpr(field.Type)
+	}
+	g.printf(")")
+}
+
+// typeExpr prints the provided type expression verbatim, or the type argument
+// of a type parameter.
+func (g *generator) typeExpr(expr ast.Expr) {
+	typ := g.pkg.TypesInfo.TypeOf(expr)
+	if arg := g.typ(typ); arg != typ {
+		g.printf("%s", g.typeString(arg))
+		return
+	}
+	g.node(expr)
+}
+
+// typeString returns the representation of the provided type in the
+// package of the derived function.
+func (g *generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg == g.pkg.Types {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+// node prints the provided node verbatim.
+func (g *generator) node(node ast.Node) {
+	if g.err != nil {
+		return
+	}
+	g.err = printer.Fprint(g.w, g.pkg.Fset, node)
+}
+
+// number prints a dual number literal with the provided real part.
+func (g *generator) number(real string) {
+	g.open()
+	switch g.order {
+	case 1, 2:
+		g.printf("%s", real)
+	default:
+		g.printf("%s", strings.TrimSpace(real))
+	}
+	g.close()
+}
+
+// seed prints the dual number seeding the provided variable.
+func (g *generator) seed(name string) {
+	if types.Identical(g.typ(g.xvar.Type()), types.Typ[types.Float32]) {
+		name = "float64(" + name + ")"
+	}
+	switch g.order {
+	case 1:
+		g.open()
+		g.printf("%s, Emag:1", name)
+		g.close()
+	case 2:
+		g.open()
+		g.printf("%s, E1mag:1, E2mag:1", name)
+		g.close()
+	default:
+		g.printf("taylor.Var(%s, %d)", name, g.order)
+	}
+}
+
+// open prints the opening part of a dual number literal, up to its real part.
+// Literals are parenthesized inside control clauses to avoid parsing ambiguities.
+func (g *generator) open() {
+	if g.header {
+		g.printf("(")
+	}
+	switch g.order {
+	case 1, 2:
+		g.printf("%s.Number{Real:", g.dpkg())
+	default:
+		g.printf("%s.Number{", g.dpkg())
+	}
+}
+
+// close prints the closing part of a dual number literal.
+func (g *generator) close() {
+	g.printf("}")
+	if g.header {
+		g.printf(")")
+	}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.w, format, args...)
+}
+
+func (g *generator) tab() {
+	g.printf("%s", strings.Repeat("\t", g.indent))
+}
+
+// object returns the object denoted by the provided identifier.
+func (g *generator) object(id *ast.Ident) types.Object {
+	if obj, ok := g.pkg.TypesInfo.Defs[id]; ok {
+		return obj
+	}
+	return g.pkg.TypesInfo.Uses[id]
+}
+
+// isLifted returns whether the provided expression is a variable holding
+// dual numbers, or an element of such a variable.
+func (g *generator) isLifted(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return g.lifted[g.object(expr)]
+	case *ast.IndexExpr:
+		return g.isLifted(expr.X)
+	}
+	return false
+}
+
+// isInput returns whether the provided expression is the input variable.
+func (g *generator) isInput(expr ast.Expr) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && g.object(id) == g.in
+}
+
+// depends returns whether the value of the provided expression may depend
+// on the seeded variable: whether it is not a non-differentiated value.
+func (g *generator) depends(expr ast.Expr) bool {
+	return !g.isConstant(expr)
+}
+
+// isConstant returns whether the provided expression is a non-differentiated
+// value: a literal, a constant, a variable of another package, a package-level
+// variable or a local variable not holding dual numbers, or an operation,
+// a conversion or a call whose operands are all such values.
+// Calls to len and cap are non-differentiated values.
+func (g *generator) isConstant(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		switch obj := g.object(expr).(type) {
+		case *types.Const, *types.Nil:
+			return true
+		case *types.Var:
+			return obj != g.xvar && !g.lifted[obj]
+		}
+	case *ast.ParenExpr:
+		return g.isConstant(expr.X)
+	case *ast.UnaryExpr:
+		switch expr.Op {
+		case token.ADD, token.SUB, token.NOT, token.XOR:
+			return g.isConstant(expr.X)
+		}
+	case *ast.BinaryExpr:
+		return g.isConstant(expr.X) && g.isConstant(expr.Y)
+	case *ast.SelectorExpr:
+		if g.importPath(expr.X) != "" {
+			switch g.pkg.TypesInfo.Uses[expr.Sel].(type) {
+			case *types.Const, *types.Var:
+				return true
+			}
+			return false
+		}
+		// Fields of non-differentiated values.
+		_, ok := g.pkg.TypesInfo.Uses[expr.Sel].(*types.Var)
+		return ok && g.isConstant(expr.X)
+	case *ast.IndexExpr:
+		return g.isConstant(expr.X) && g.isConstant(expr.Index)
+	case *ast.CompositeLit:
+		for _, elt := range expr.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			if !g.isConstant(elt) {
+				return false
+			}
+		}
+		return true
+	case *ast.CallExpr:
+		if g.isBuiltin(expr) {
+			switch expr.Fun.(*ast.Ident).Name {
+			case "len", "cap":
+				return true
+			}
+		} else if !g.pkg.TypesInfo.Types[expr.Fun].IsType() && !g.isFunc(expr.Fun) && !g.isConstant(expr.Fun) {
+			return false
+		}
+		for _, arg := range expr.Args {
+			if !g.isConstant(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isFunc returns whether the provided expression denotes a function,
+// possibly instantiated, or a method of a non-differentiated value.
+func (g *generator) isFunc(expr ast.Expr) bool {
+	switch expr := unparen(expr).(type) {
+	case *ast.Ident:
+		_, ok := g.object(expr).(*types.Func)
+		return ok
+	case *ast.SelectorExpr:
+		if _, ok := g.pkg.TypesInfo.Uses[expr.Sel].(*types.Func); !ok {
+			return false
+		}
+		return g.importPath(expr.X) != "" || g.isConstant(expr.X)
+	case *ast.IndexExpr:
+		return g.isFunc(expr.X)
+	case *ast.IndexListExpr:
+		return g.isFunc(expr.X)
+	}
+	return false
+}
+
+// activate lifts the local variables of the provided function body assigned
+// values that depend on the seeded variable, or on other lifted variables:
+// float64 variables, and slices and arrays of float64 values. Other local
+// variables hold plain values.
+func (g *generator) activate(body *ast.BlockStmt) {
+	changed := true
+	lift := func(lhs, rhs ast.Expr) {
+		id, ok := lhs.(*ast.Ident)
+		if !ok {
+			return
+		}
+		obj := g.object(id)
+		switch {
+		case obj == nil, obj == g.xvar, obj == g.in, g.lifted[obj]:
+			return
+		case obj.Parent() == g.pkg.Types.Scope():
+			return
+		case !g.isFloat(id) && !g.isFloatSlice(id):
+			return
+		case rhs != nil && !g.depends(rhs):
+			return
+		}
+		g.lifted[obj] = true
+		changed = true
+	}
+	for changed {
+		changed = false
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.AssignStmt:
+				for i, lhs := range n.Lhs {
+					switch {
+					case len(n.Lhs) == len(n.Rhs):
+						lift(lhs, n.Rhs[i])
+					case i == 0:
+						// math.Lgamma also returns the sign of its value.
+						lift(lhs, n.Rhs[0])
+					}
+				}
+			case *ast.RangeStmt:
+				if n.Value != nil && g.isDualSlice(n.X) {
+					lift(n.Value, nil)
+				}
+			case *ast.ValueSpec:
+				for _, v := range n.Values {
+					if g.depends(v) {
+						for _, name := range n.Names {
+							lift(name, nil)
+						}
+						break
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// isDualSlice returns whether the provided expression is a slice or an array
+// of float64 values holding dual numbers, or depending on the seeded variable.
+func (g *generator) isDualSlice(expr ast.Expr) bool {
+	return g.isFloatSlice(expr) && (g.isLifted(expr) || g.depends(expr))
+}
+
+// isFloatSlice returns whether the provided expression is a slice or an array
+// of float64 values.
+func (g *generator) isFloatSlice(expr ast.Expr) bool {
+	typ := g.pkg.TypesInfo.TypeOf(expr)
+	if typ == nil {
+		return false
+	}
+	switch typ := g.typ(typ).Underlying().(type) {
+	case *types.Slice:
+		return g.isFloatType(typ.Elem())
+	case *types.Array:
+		return g.isFloatType(typ.Elem())
+	}
+	return false
+}
+
+// isDiscrete returns whether the provided expression is of an integer or
+// boolean type, whose values are piecewise constant.
+func (g *generator) isDiscrete(expr ast.Expr) bool {
+	typ := g.pkg.TypesInfo.TypeOf(expr)
+	if typ == nil {
+		return false
+	}
+	basic, ok := g.typ(typ).Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsInteger|types.IsBoolean) != 0
+}
+
+// isFloat returns whether the provided expression is of the type of the
+// differentiated values.
+func (g *generator) isFloat(expr ast.Expr) bool {
+	if id, ok := expr.(*ast.Ident); ok {
+		obj := g.object(id)
+		if obj == nil {
+			return false
+		}
+		return g.isFloatType(obj.Type())
+	}
+	return g.isFloatType(g.pkg.TypesInfo.TypeOf(expr))
+}
+
+// isFloatType returns whether the provided type is the type of the
+// differentiated values: float64 or complex128, and both float32 and
+// float64 for float32 functions, which call math functions on float64 values.
+func (g *generator) isFloatType(typ types.Type) bool {
+	typ = g.typ(typ)
+	if types.Identical(typ, g.scalar) {
+		return true
+	}
+	return types.Identical(g.scalar, types.Typ[types.Float32]) &&
+		types.Identical(typ, types.Typ[types.Float64])
+}
+
+// isFloat32 returns whether the provided expression is of type float32.
+// Dual numbers hold the values of float32 expressions as float64 values.
+func (g *generator) isFloat32(expr ast.Expr) bool {
+	if id, ok := expr.(*ast.Ident); ok {
+		obj := g.object(id)
+		return obj != nil && types.Identical(g.typ(obj.Type()), types.Typ[types.Float32])
+	}
+	return types.Identical(g.typ(g.pkg.TypesInfo.TypeOf(expr)), types.Typ[types.Float32])
+}
+
+// typ returns the type argument of the provided type if it is a type
+// parameter of the derived generic function, and the provided type otherwise.
+func (g *generator) typ(typ types.Type) types.Type {
+	return subst(g.targs, typ)
+}
+
+// subst returns the type argument of the provided type if it is one of
+// the type parameters in targs, and the provided type otherwise.
+func subst(targs map[*types.TypeParam]types.Type, typ types.Type) types.Type {
+	if tp, ok := typ.(*types.TypeParam); ok && targs[tp] != nil {
+		return targs[tp]
+	}
+	return typ
+}
+
+// typeArg returns the type argument of the type parameter denoted by
+// the provided identifier, or nil.
+func (g *generator) typeArg(id *ast.Ident) types.Type {
+	tn, ok := g.object(id).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	if arg := g.typ(tn.Type()); arg != tn.Type() {
+		return arg
+	}
+	return nil
+}
+
+// result returns the conversion of the provided float64 expression
+// to the result type of the derived function.
+func (g *generator) result(expr string) string {
+	if types.Identical(g.scalar, types.Typ[types.Float32]) {
+		return "float32(" + expr + ")"
+	}
+	return expr
+}
+
+// isComplex returns whether complex functions are derived.
+func (g *generator) isComplex() bool {
+	return types.Identical(g.scalar, types.Typ[types.Complex128])
+}
+
+// mathPath returns the import path of the package whose functions are
+// mapped to the dual number functions.
+func (g *generator) mathPath() string {
+	if g.isComplex() {
+		return "math/cmplx"
+	}
+	return "math"
+}
+
+// mathName returns the name under which the file declaring the derived
+// function imports the math package, or "math" if it does not import it.
+// The generated code refers to the math package under that name.
+func (g *generator) mathName() string {
+	file := g.pkg.Fset.File(g.fct.Pos())
+	for _, f := range g.pkg.Syntax {
+		if g.pkg.Fset.File(f.Pos()) != file {
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if path == "math" && spec.Name != nil && token.IsIdentifier(spec.Name.Name) && spec.Name.Name != "_" {
+				return spec.Name.Name
+			}
+		}
+	}
+	return "math"
+}
+
+// importPath returns the import path of the package denoted by the provided
+// expression, as resolved by the type checker, or "" if expr does not denote
+// an imported package.
+func (g *generator) importPath(expr ast.Expr) string {
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	obj, ok := g.pkg.TypesInfo.Uses[id]
+	if !ok && id.Name == "math" {
+		// Identifiers created by the generator, as in the expressions
+		// of symbolic derivatives, refer to the math package.
+		return "math"
+	}
+	pkg, ok := obj.(*types.PkgName)
+	if !ok {
+		return ""
+	}
+	return pkg.Imported().Path()
+}
+
+// dpkg returns the name of the package providing the dual number type.
+func (g *generator) dpkg() string {
+	switch {
+	case g.mode == Reverse:
+		return "adjoint"
+	case g.isComplex():
+		return "cdual"
+	}
+	switch g.order {
+	case 1:
+		return "dual"
+	case 2:
+		return "hyperdual"
+	}
+	return "taylor"
+}
+
+// realPart returns the selector of the real part of a dual number.
+func (g *generator) realPart() string {
+	switch g.order {
+	case 1, 2:
+		return ".Real"
+	}
+	return "[0]"
+}
+
+// f1x, f1x32, c1x, fnx and fmnx are the pre-computed signatures of
+// 'func(float64) float64', 'func(float32) float32', 'func(complex128) complex128',
+// 'func([]float64) float64' and 'func(dst, x []float64)'.
+// These will be checked against to make sure Derivative is called on valid functions.
+var f1x, f1x32, c1x, fnx, fmnx *types.Func
+
+func init() {
+	const variadic = false
 	f64 := types.NewParam(0, nil, "x", types.Typ[types.Float64])
+	s64 := types.NewParam(0, nil, "x", types.NewSlice(types.Typ[types.Float64]))
 
 	sig := types.NewSignature(nil, types.NewTuple(f64), types.NewTuple(f64), variadic)
 	f1x = types.NewFunc(0, nil, "f1x", sig)
+
+	f32 := types.NewParam(0, nil, "x", types.Typ[types.Float32])
+	sig = types.NewSignature(nil, types.NewTuple(f32), types.NewTuple(f32), variadic)
+	f1x32 = types.NewFunc(0, nil, "f1x32", sig)
+
+	c128 := types.NewParam(0, nil, "z", types.Typ[types.Complex128])
+	sig = types.NewSignature(nil, types.NewTuple(c128), types.NewTuple(c128), variadic)
+	c1x = types.NewFunc(0, nil, "c1x", sig)
+
+	sig = types.NewSignature(nil, types.NewTuple(s64), types.NewTuple(f64), variadic)
+	fnx = types.NewFunc(0, nil, "fnx", sig)
+
+	sig = types.NewSignature(nil, types.NewTuple(s64, s64), nil, variadic)
+	fmnx = types.NewFunc(0, nil, "fmnx", sig)
 }
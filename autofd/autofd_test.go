@@ -18,15 +18,31 @@ func TestDerivative(t *testing.T) {
 		if test.name.Deriv != "" {
 			name += "-" + test.name.Deriv
 		}
-		switch {
-		case test.d2x:
-			name += "-d2x"
-		default:
-			name += "-d1x"
+		if test.name.Wrt != "" {
+			name += "-wrt-" + test.name.Wrt
+		}
+		switch test.name.Mode {
+		case autofd.Reverse:
+			name += "-reverse"
+		case autofd.Symbolic:
+			name += "-symbolic"
+		}
+		switch test.name.Subgradient {
+		case autofd.LeftSubgradient:
+			name += "-left"
+		case autofd.RightSubgradient:
+			name += "-right"
+		case autofd.ZeroSubgradient:
+			name += "-zero"
+		}
+		order := test.order
+		if order == 0 {
+			order = 1
 		}
+		name += fmt.Sprintf("-d%dx", order)
 		t.Run(name, func(t *testing.T) {
 			buf := new(strings.Builder)
-			err := autofd.Derivative(buf, test.name, test.d2x)
+			err := autofd.Derivative(buf, test.name, order)
 			switch {
 			case err != nil && test.err != nil:
 				if got, want := err.Error(), test.err.Error(); got != want {
@@ -45,215 +61,3746 @@ func TestDerivative(t *testing.T) {
 	}
 }
 
-var derivativeTests = []struct {
-	name autofd.Func
-	d2x  bool
-	want string
-	err  error
-}{
-	// first derivatives
+func TestFile(t *testing.T) {
+	const path = "gonum.org/v1/tools/autofd/internal/testfunc"
+	for _, test := range []struct {
+		name  string
+		pkg   string
+		fcts  []autofd.Func
+		order int
+		want  string
+		err   error
+	}{
+		{
+			name: "first derivatives",
+			fcts: []autofd.Func{
+				{Path: path, Name: "F1"},
+				{Path: path, Name: "F7"},
+			},
+			order: 1,
+			want: `// Code generated by autofd; DO NOT EDIT.
+
+package testfunc
+
+import (
+	"gonum.org/v1/gonum/num/dual"
+)
+
+func DerivF1(x float64) float64 {
+	xd := dual.Number{Real: x, Emag: 1}
+	v := dual.Mul(xd, xd)
+	return v.Emag
+}
+
+func DerivF7(x float64) float64 {
+	xd := dual.Number{Real: x, Emag: 1}
+	v := dual.Cos(dual.Scale(6.283185307179586, xd))
+	return v.Emag
+}
+`,
+		},
+		{
+			name: "shared helpers",
+			pkg:  "deriv",
+			fcts: []autofd.Func{
+				{Path: path, Name: "P2[float64]", Wrt: "x"},
+				{Path: path, Name: "P2[float32]", Wrt: "x"},
+				{Path: path, Name: "G1"},
+			},
+			order: 2,
+			want: `// Code generated by autofd; DO NOT EDIT.
+
+package deriv
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/num/hyperdual"
+)
+
+func DerivP2_float64_x(x float64, n int) (d1, d2 float64) {
+	xd := hyperdual.Number{Real: x, E1mag: 1, E2mag: 1}
+	s := hyperdual.Number{Real: float64(0)}
+	for i := 0; i < n; i++ {
+		s = hyperdual.Add(s, hyperdual.Scale(1.0/float64(i+1), hyperdual_psq(xd)))
+	}
+	v := s
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_psq(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, x)
+}
+
+func DerivP2_float32_x(x float32, n int) (d1, d2 float32) {
+	xd := hyperdual.Number{Real: float64(x), E1mag: 1, E2mag: 1}
+	s := hyperdual.Number{Real: float64(float32(0))}
+	for i := 0; i < n; i++ {
+		s = hyperdual.Add(s, hyperdual.Scale(1.0/float64(float32(i+1)), hyperdual_psq(xd)))
+	}
+	v := s
+	return float32(v.E1mag), float32(v.E1E2mag)
+}
+
+func DerivG1(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		return hyperdual.Add(hyperdual.Add(hyperdual.Mul(x[0], x[0]), hyperdual.Mul(hyperdual.Scale(3, x[0]), x[1])), hyperdual.Sin(x[1]))
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].E1mag = 1
+		for j := i; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i].E1mag = 0
+	}
+}
+`,
+		},
+		{
+			name: "imported packages",
+			pkg:  "deriv",
+			fcts: []autofd.Func{
+				{Path: path, Name: "F32"},
+				{Path: path, Name: "F33", Mode: autofd.Symbolic},
+			},
+			order: 1,
+			want: `// Code generated by autofd; DO NOT EDIT.
+
+package deriv
+
+import (
+	m "math"
+
+	"gonum.org/v1/gonum/num/dual"
+	"gonum.org/v1/tools/autofd/internal/physconst"
+)
+
+func DerivF32(x float64) float64 {
+	xd := dual.Number{Real: x, Emag: 1}
+	v := dual.Add(dual.Add(dual.Scale(1e23, dual.Scale(physconst.Boltzmann, xd)), dual.Scale(physconst.Scale, dual.Sin(xd))), dual.Scale(physconst.Temperatures[1], dual.Inv((dual.Add(dual.Mul(xd, xd), dual.Number{Real: 1})))))
+	return v.Emag
+}
+
+func DerivF33(x float64) float64 {
+	return m.Cos(physconst.Scale*x)*physconst.Scale + x/m.Hypot(x, physconst.Boltzmann) + physconst.Temperatures[0]/100*m.Pow(x, physconst.Temperatures[0]/100-1)
+}
+`,
+		},
+		{
+			name:  "other package",
+			pkg:   "deriv",
+			fcts:  []autofd.Func{{Path: path, Name: "F17"}},
+			order: 1,
+			err:   fmt.Errorf("could not generate derivative of F17: can not refer to coeffs of package testfunc from package deriv"),
+		},
+		{
+			name:  "no function",
+			order: 1,
+			err:   fmt.Errorf("no function to derive"),
+		},
+		{
+			name: "invalid function",
+			fcts: []autofd.Func{
+				{Path: path, Name: "F1"},
+				{Path: path, Name: "ErrF1"},
+			},
+			order: 1,
+			err:   fmt.Errorf("could not create derivative generator for ErrF1: invalid function signature for ErrF1"),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(strings.Builder)
+			err := autofd.File(buf, test.pkg, test.fcts, test.order)
+			switch {
+			case err != nil && test.err != nil:
+				if got, want := err.Error(), test.err.Error(); got != want {
+					t.Fatalf("invalid error.\ngot= %v\nwant=%v\n", got, want)
+				}
+			case err != nil && test.err == nil:
+				t.Fatalf("could not generate file: %+v", err)
+			case err == nil && test.err != nil:
+				t.Fatalf("got=%v, want=%v", err, test.err)
+			case err == nil && test.err == nil:
+				if got, want := buf.String(), test.want; got != want {
+					t.Fatalf("invalid file:\ngot:\n%s\nwant:\n%s\n", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileTest(t *testing.T) {
+	const path = "gonum.org/v1/tools/autofd/internal/testfunc"
+	for _, test := range []struct {
+		name  string
+		fcts  []autofd.Func
+		order int
+		want  string
+		err   error
+	}{
+		{
+			name: "first derivatives",
+			fcts: []autofd.Func{
+				{Path: path, Name: "F21", Wrt: "y"},
+				{Path: path, Name: "S1"},
+				{Path: path, Name: "C1"},
+				{Path: path, Name: "J1", Mode: autofd.Reverse},
+			},
+			order: 1,
+			want: `// Code generated by autofd; DO NOT EDIT.
+
+package testfunc
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"gonum.org/v1/gonum/diff/fd"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDerivF21_y(t *testing.T) {
+	f := func(x float64) float64 {
+		return F21(0.7, 2, x)
+	}
+	for _, x := range []float64{-1.3, -0.4, 0.3, 0.9, 2.1} {
+		d1 := DerivF21_y(0.7, 2, x)
+		want := fd.Derivative(f, x, &fd.Settings{Formula: fd.Central})
+		if !math.IsNaN(want) && math.Abs(d1-want) > 1e-6*math.Max(1, math.Abs(want)) {
+			t.Errorf("unexpected derivative of order 1 of F21 at %v: got=%v want=%v", x, d1, want)
+		}
+	}
+}
+
+func TestDerivS1(t *testing.T) {
+	f := func(x float64) float64 {
+		return float64(S1(float32(x)))
+	}
+	for _, x := range []float64{-1.3, -0.4, 0.3, 0.9, 2.1} {
+		d1 := DerivS1(float32(x))
+		want := fd.Derivative(f, x, &fd.Settings{Formula: fd.Central, Step: 1e-2})
+		if !math.IsNaN(want) && math.Abs(float64(d1)-want) > 1e-2*math.Max(1, math.Abs(want)) {
+			t.Errorf("unexpected derivative of order 1 of S1 at %v: got=%v want=%v", x, d1, want)
+		}
+	}
+}
+
+func TestDerivC1(t *testing.T) {
+	f := func(x complex128) complex128 {
+		return C1(x)
+	}
+	for _, x := range []complex128{0.3 + 0.4i, -1.2 + 0.5i, 2 - 1i} {
+		d1 := DerivC1(x)
+		want := complex(
+			fd.Derivative(func(h float64) float64 { return real(f(x + complex(h, 0))) }, 0, &fd.Settings{Formula: fd.Central}),
+			fd.Derivative(func(h float64) float64 { return imag(f(x + complex(h, 0))) }, 0, &fd.Settings{Formula: fd.Central}),
+		)
+		if !cmplx.IsNaN(want) && cmplx.Abs(d1-want) > 1e-6*math.Max(1, cmplx.Abs(want)) {
+			t.Errorf("unexpected derivative of C1 at %v: got=%v want=%v", x, d1, want)
+		}
+	}
+}
+
+func TestDerivJ1(t *testing.T) {
+	f := J1
+	const m = 3
+	for _, x := range [][]float64{{0.3, -0.4}, {-1.3, 0.9}} {
+		got := mat.NewDense(m, len(x), nil)
+		DerivJ1(got, x)
+		want := mat.NewDense(m, len(x), nil)
+		fd.Jacobian(want, f, x, &fd.JacobianSettings{Formula: fd.Central})
+		ok := true
+		for i := 0; i < m; i++ {
+			for j := range x {
+				w := want.At(i, j)
+				if !math.IsNaN(w) && math.Abs(got.At(i, j)-w) > 1e-6*math.Max(1, math.Abs(w)) {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			t.Errorf("unexpected Jacobian of J1 at %v:\ngot=%v\nwant=%v", x, mat.Formatted(got), mat.Formatted(want))
+		}
+	}
+}
+`,
+		},
+		{
+			name: "second derivatives",
+			fcts: []autofd.Func{
+				{Path: path, Name: "T1.F"},
+				{Path: path, Name: "P1[float64]"},
+				{Path: path, Name: "G1"},
+			},
+			order: 2,
+			want: `// Code generated by autofd; DO NOT EDIT.
+
+package testfunc
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/diff/fd"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDerivT1_F(t *testing.T) {
+	var recv T1
+	f := func(x float64) float64 {
+		return recv.F(x)
+	}
+	for _, x := range []float64{-1.3, -0.4, 0.3, 0.9, 2.1} {
+		d1, d2 := DerivT1_F(x)
+		want := fd.Derivative(f, x, &fd.Settings{Formula: fd.Central})
+		if !math.IsNaN(want) && math.Abs(d1-want) > 1e-6*math.Max(1, math.Abs(want)) {
+			t.Errorf("unexpected derivative of order 1 of T1.F at %v: got=%v want=%v", x, d1, want)
+		}
+		want = fd.Derivative(func(x float64) float64 {
+			d, _ := DerivT1_F(x)
+			return d
+		}, x, &fd.Settings{Formula: fd.Central})
+		if !math.IsNaN(want) && math.Abs(d2-want) > 1e-6*math.Max(1, math.Abs(want)) {
+			t.Errorf("unexpected derivative of order 2 of T1.F at %v: got=%v want=%v", x, d2, want)
+		}
+	}
+}
+
+func TestDerivP1_float64(t *testing.T) {
+	f := func(x float64) float64 {
+		return P1[float64](x)
+	}
+	for _, x := range []float64{-1.3, -0.4, 0.3, 0.9, 2.1} {
+		d1, d2 := DerivP1_float64(x)
+		want := fd.Derivative(f, x, &fd.Settings{Formula: fd.Central})
+		if !math.IsNaN(want) && math.Abs(d1-want) > 1e-6*math.Max(1, math.Abs(want)) {
+			t.Errorf("unexpected derivative of order 1 of P1[float64] at %v: got=%v want=%v", x, d1, want)
+		}
+		want = fd.Derivative(func(x float64) float64 {
+			d, _ := DerivP1_float64(x)
+			return d
+		}, x, &fd.Settings{Formula: fd.Central})
+		if !math.IsNaN(want) && math.Abs(d2-want) > 1e-6*math.Max(1, math.Abs(want)) {
+			t.Errorf("unexpected derivative of order 2 of P1[float64] at %v: got=%v want=%v", x, d2, want)
+		}
+	}
+}
+
+func TestDerivG1(t *testing.T) {
+	f := G1
+	for _, x := range [][]float64{{0.3, -0.4}, {-1.3, 0.9}} {
+		got := mat.NewSymDense(len(x), nil)
+		DerivG1(got, x)
+		want := mat.NewSymDense(len(x), nil)
+		fd.Hessian(want, f, x, &fd.Settings{Formula: fd.Central})
+		ok := true
+		for i := range x {
+			for j := i; j < len(x); j++ {
+				w := want.At(i, j)
+				if !math.IsNaN(w) && math.Abs(got.At(i, j)-w) > 1e-3*math.Max(1, math.Abs(w)) {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			t.Errorf("unexpected Hessian of G1 at %v:\ngot=%v\nwant=%v", x, mat.Formatted(got), mat.Formatted(want))
+		}
+	}
+}
+`,
+		},
+		{
+			name:  "no function",
+			order: 1,
+			err:   fmt.Errorf("no function to derive"),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(strings.Builder)
+			err := autofd.FileTest(buf, "", test.fcts, test.order)
+			switch {
+			case err != nil && test.err != nil:
+				if got, want := err.Error(), test.err.Error(); got != want {
+					t.Fatalf("invalid error.\ngot= %v\nwant=%v\n", got, want)
+				}
+			case err != nil && test.err == nil:
+				t.Fatalf("could not generate test file: %+v", err)
+			case err == nil && test.err != nil:
+				t.Fatalf("got=%v, want=%v", err, test.err)
+			case err == nil && test.err == nil:
+				if got, want := buf.String(), test.want; got != want {
+					t.Fatalf("invalid test file:\ngot:\n%s\nwant:\n%s\n", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPackage(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want string
+		err  error
+	}{
+		{
+			path: "gonum.org/v1/tools/autofd/internal/testfunc",
+			want: `// Code generated by autofd; DO NOT EDIT.
+
+package testfunc
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/num/dual"
+	"gonum.org/v1/gonum/num/hyperdual"
+	"gonum.org/v1/tools/autofd/adjoint"
+	"gonum.org/v1/tools/autofd/taylor"
+)
+
+func DerivF1(x float64) float64 {
+	xd := dual.Number{Real: x, Emag: 1}
+	v := dual.Mul(xd, xd)
+	return v.Emag
+}
+
+func DxF7(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real: x, E1mag: 1, E2mag: 1}
+	v := hyperdual.Cos(hyperdual.Scale(6.283185307179586, xd))
+	return v.E1mag, v.E1E2mag
+}
+
+func DerivF21_y(x float64, n int, y float64) float64 {
+	yd := dual.Number{Real: y, Emag: 1}
+	v := dual.Scale(math.Pow(x, float64(n)), yd)
+	return v.Emag
+}
+
+func DerivF23(x float64) float64 {
+	if x*x+1 > 2 {
+		return (2*x - 2*x*math.Log(x*x+1)) / ((x*x + 1) * (x*x + 1))
+	}
+	return x / math.Sqrt(x*x+1)
+}
+
+func DxF28(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real: x, E1mag: 1, E2mag: 1}
+	relu := hyperdual_math_Max_left(hyperdual.Number{Real: 0}, hyperdual.Sub(hyperdual.Mul(xd, xd), hyperdual.Number{Real: 0.5}))
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Mul(relu, relu), hyperdual_math_Max_left(hyperdual.Number{Real: 0}, hyperdual.Sub(hyperdual.Number{Real: 1}, xd))), hyperdual_math_Min_left(xd, hyperdual.Sub(hyperdual.Scale(2, xd), hyperdual.Number{Real: 1})))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Max_left(u, v hyperdual.Number) hyperdual.Number {
+	if d := hyperdual.Sub(u, v); d.Real > 0 || d.Real == 0 && (d.E1mag < 0 || d.E1mag == 0 && d.E1E2mag > 0) {
+		return u
+	}
+	return v
+}
+
+func hyperdual_math_Min_left(u, v hyperdual.Number) hyperdual.Number {
+	if d := hyperdual.Sub(u, v); d.Real > 0 || d.Real == 0 && (d.E1mag < 0 || d.E1mag == 0 && d.E1E2mag > 0) {
+		return v
+	}
+	return u
+}
+
+func DerivG1(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []adjoint.Number) adjoint.Number {
+		return adjoint.Add(adjoint.Add(adjoint.Mul(x[0], x[0]), adjoint.Mul(adjoint.Scale(3, x[0]), x[1])), adjoint.Sin(x[1]))
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(x))
+	for i, v := range x {
+		xd[i] = tape.Var(v)
+	}
+	tape.Gradient(grad, fn(xd))
+}
+
+func DerivV1(x float64) float64 {
+	xd := dual.Number{Real: x, Emag: 1}
+	v := dual.Mul(xd, dual.Exp(xd))
+	return v.Emag
+}
+
+func DerivT1_F(x float64) float64 {
+	xd := dual.Number{Real: x, Emag: 1}
+	v := dual.Add(dual.Add(dual.Scale(2, xd), dual.Mul(dual.Scale(3, xd), xd)), dual.Scale(4, dual.PowReal(xd, 3)))
+	return v.Emag
+}
+
+func DxF(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Add(taylor.Add(taylor.Scale(2, xd), taylor.Mul(taylor.Scale(3, xd), xd)), taylor.Scale(4, taylor.PowReal(xd, 3)))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+		},
+		{
+			path: "gonum.org/v1/tools/autofd/internal/errdirective",
+			err:  fmt.Errorf(`invalid directive for F2 at errdirective.go:13: invalid value "two" for flag -order: parse error`),
+		},
+		{
+			path: "gonum.org/v1/tools/autofd/internal/errorder",
+			err:  fmt.Errorf(`invalid directive for F1 at errorder.go:8: -d2 conflicts with -order 3`),
+		},
+		{
+			path: "gonum.org/v1/tools/autofd/internal/errrule",
+			err:  fmt.Errorf(`could not create derivative generator for F1: invalid rule at errrule.go:13: invalid function signature for df`),
+		},
+		{
+			path: "gonum.org/v1/tools/autofd/taylor",
+			err:  fmt.Errorf("no function to derive"),
+		},
+		{
+			path: "gonum.org/v1/tools/autofd/internal/testfuncXXX",
+			err:  fmt.Errorf(`could not find package "gonum.org/v1/tools/autofd/internal/testfuncXXX"`),
+		},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			buf := new(strings.Builder)
+			err := autofd.Package(buf, test.path)
+			switch {
+			case err != nil && test.err != nil:
+				if got, want := err.Error(), test.err.Error(); got != want {
+					t.Fatalf("invalid error.\ngot= %v\nwant=%v\n", got, want)
+				}
+			case err != nil && test.err == nil:
+				t.Fatalf("could not generate file: %+v", err)
+			case err == nil && test.err != nil:
+				t.Fatalf("got=%v, want=%v", err, test.err)
+			case err == nil && test.err == nil:
+				if got, want := buf.String(), test.want; got != want {
+					t.Fatalf("invalid file:\ngot:\n%s\nwant:\n%s\n", got, want)
+				}
+			}
+		})
+	}
+}
+
+var derivativeTests = []struct {
+	name  autofd.Func
+	order int // order of the highest derivative, 1 if zero.
+	want  string
+	err   error
+}{
+	// first derivatives
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1"},
+		want: `func DerivF1(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Mul(xd, xd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1", Deriv: "DxF1"},
+		want: `func DxF1(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Mul(xd, xd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.F", Deriv: "DxF"},
+		want: `func DxF(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Add(dual.Scale(2, xd), dual.Mul(dual.Scale(3, xd), xd)), dual.Scale(4, dual.PowReal(xd, 3)))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T2.F", Deriv: "DxF"},
+		want: `func DxF(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Add(dual.Scale(2, xd), dual.Mul(dual.Scale(3, xd), xd)), dual.Scale(4, dual.PowReal(xd, 3)))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F2"},
+		want: `func DerivF2(y float64) float64 {
+	yd := dual.Number{Real:y, Emag:1}
+	v := dual.Mul(yd, yd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F3"},
+		want: `func DerivF3(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Mul(dual.Scale(2, xd), xd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F4"},
+		want: `func DerivF4(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Scale(2, dual.Inv((dual.Mul(xd, xd))))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F5"},
+		want: `func DerivF5(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Scale(2, dual.Inv((dual.Mul(xd, dual.Scale(-1, xd)))))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F6"},
+		want: `func DerivF6(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Sub(dual.Add(dual.Number{Real:2}, xd), xd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F7"},
+		want: `func DerivF7(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Cos(dual.Scale(6.283185307179586, xd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F8"},
+		want: `func DerivF8(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Mul(dual.Exp(xd), dual.Inv(dual.Sqrt(dual.Add(dual.PowReal(dual.Sin(xd), 3), dual.PowReal(dual.Cos(xd), 3)))))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F9"},
+		want: `func DerivF9(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Scale(pi, xd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F10"},
+		want: `func DerivF10(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	a := dual.Sin(xd)
+	v := dual.Mul(a, a)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F11"},
+		want: `func DerivF11(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	var a dual.Number
+	a = dual.Scale(2, xd)
+	var b = dual.Add(a, xd)
+	b = dual.Mul(b, a)
+	v := b
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F12"},
+		want: `func DerivF12(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	const c = 3
+	a, b := xd, 2.0
+	a = dual.Add(a, dual.Number{Real:b})
+	v := dual.Scale(b, dual.Scale(c, a))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F13"},
+		want: `func DerivF13(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	if x < 0 {
+		v := dual.Scale(-1, xd)
+		return v.Emag
+	}
+	v := xd
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F14"},
+		want: `func DerivF14(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	switch {
+	case x < 0:
+		v := dual.Scale(-1, xd)
+		return v.Emag
+	default:
+		v := xd
+		return v.Emag
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F15"},
+		want: `func DerivF15(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	a := dual.Mul(xd, xd)
+	switch {
+	case a.Real > 4:
+		a = dual.Number{Real:4}
+	case a.Real < 1:
+		a = dual.Scale(2, a)
+	}
+	if b := dual.Sub(a, xd); b.Real > 0 {
+		v := b
+		return v.Emag
+	} else if b.Real < -1 {
+		v := dual.Mul(dual.Scale(-1, b), xd)
+		return v.Emag
+	} else {
+		v := a
+		return v.Emag
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F16"},
+		want: `func DerivF16(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	a := dual.Sin(xd)
+	switch n := 2.0; math.Floor(a.Real * n) {
+	case -2, -1:
+		a = dual.Mul(a, xd)
+		fallthrough
+	case 0:
+		a = dual.Add(a, dual.Number{Real:1})
+	}
+	v := a
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F17"},
+		want: `func DerivF17(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	s := dual.Number{Real:0.0}
+	for i := 0; i < len(coeffs); i++ {
+		s = dual.Add(s, dual.Scale(coeffs[i], dual.PowReal(xd, float64(i))))
+	}
+	v := s
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F18"},
+		want: `func DerivF18(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	p := dual.Number{Real:1.0}
+	for i, c := range coeffs {
+		if i == 2 {
+			continue
+		}
+		p = dual.Mul(p, dual.Add(dual.Scale(c, xd), dual.Number{Real:1}))
+	}
+	v := p
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F19"},
+		want: `func DerivF19(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			v := dual.Scale(-1, xd)
+			return v.Emag
+		}
+	}
+	v := xd
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F20"},
+		want: `func DerivF20(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	a := xd
+	n := 0
+outer:
+	for a.Real < 10 {
+		for t := 0.5; t < 2; t *= 2 {
+			a = dual.Add(a, dual.Scale(t, xd))
+			n++
+			if n > 20 {
+				break outer
+			}
+		}
+	}
+	v := a
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G1"},
+		want: `func DerivG1(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		return dual.Add(dual.Add(dual.Mul(x[0], x[0]), dual.Mul(dual.Scale(3, x[0]), x[1])), dual.Sin(x[1]))
+	}
+	xd := make([]dual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].Emag = 1
+		grad[i] = fn(xd).Emag
+		xd[i].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G2"},
+		want: `func DerivG2(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		s := dual.Number{Real:0.0}
+		for i, v := range x {
+			s = dual.Add(s, dual.Mul(dual.Scale(float64(i + 1), v), v))
+		}
+		return s
+	}
+	xd := make([]dual.Number, len(x))
+	for i1, v1 := range x {
+		xd[i1].Real = v1
+	}
+	for i1 := range xd {
+		xd[i1].Emag = 1
+		grad[i1] = fn(xd).Emag
+		xd[i1].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G3"},
+		want: `func DerivG3(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		s := dual.Number{Real:0.0}
+		for i := 0; i < len(x) - 1; i++ {
+			a := dual.Sub(dual.Number{Real:1}, x[i])
+			b := dual.Sub(x[i + 1], dual.Mul(x[i], x[i]))
+			s = dual.Add(s, dual.Add(dual.Mul(a, a), dual.Mul(dual.Scale(100, b), b)))
+		}
+		return s
+	}
+	xd := make([]dual.Number, len(x))
+	for i1, v := range x {
+		xd[i1].Real = v
+	}
+	for i1 := range xd {
+		xd[i1].Emag = 1
+		grad[i1] = fn(xd).Emag
+		xd[i1].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "J1"},
+		want: `func DerivJ1(jac *mat.Dense, x []float64) {
+	m, n := jac.Dims()
+	if n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(dst, x []dual.Number) {
+		dst[0] = dual.Mul(x[0], x[1])
+		dst[1] = dual.Add(dual.Sin(x[0]), dual.Mul(x[1], x[1]))
+		dst[2] = dual.Exp(dual.Sub(x[0], x[1]))
+	}
+	xd := make([]dual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	yd := make([]dual.Number, m)
+	for j := range xd {
+		xd[j].Emag = 1
+		fn(yd, xd)
+		for i, v := range yd {
+			jac.Set(i, j, v.Emag)
+		}
+		xd[j].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "J2"},
+		want: `func DerivJ2(jac *mat.Dense, x []float64) {
+	m, n := jac.Dims()
+	if n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(dst, x []dual.Number) {
+		for i := range dst {
+			dst[i] = dual.Number{Real:0}
+			for j, v := range x {
+				dst[i] = dual.Add(dst[i], dual.Mul(dual.Scale(float64(i + j + 1), v), v))
+			}
+		}
+	}
+	xd := make([]dual.Number, len(x))
+	for i1, v1 := range x {
+		xd[i1].Real = v1
+	}
+	yd := make([]dual.Number, m)
+	for j1 := range xd {
+		xd[j1].Emag = 1
+		fn(yd, xd)
+		for i1, v1 := range yd {
+			jac.Set(i1, j1, v1.Emag)
+		}
+		xd[j1].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "J3"},
+		want: `func DerivJ3(jac *mat.Dense, n []float64) {
+	m1, n1 := jac.Dims()
+	if n1 != len(n) {
+		panic("matrix size mismatch")
+	}
+	fn := func(dst, n []dual.Number) {
+		m := float64(len(n))
+		dst[0] = dual.Mul(dual.Scale(m, n[0]), n[1])
+		dst[1] = dual.Sub(dual.Sin(n[0]), n[1])
+	}
+	xd := make([]dual.Number, len(n))
+	for i, v := range n {
+		xd[i].Real = v
+	}
+	yd := make([]dual.Number, m1)
+	for j := range xd {
+		xd[j].Emag = 1
+		fn(yd, xd)
+		for i, v := range yd {
+			jac.Set(i, j, v.Emag)
+		}
+		xd[j].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "x"},
+		want: `func DerivF21_x(x float64, n int, y float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Scale(y, dual.PowReal(xd, float64(n)))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "y"},
+		want: `func DerivF21_y(x float64, n int, y float64) float64 {
+	yd := dual.Number{Real:y, Emag:1}
+	v := dual.Scale(math.Pow(x, float64(n)), yd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF1", Wrt: "y"},
+		want: `func DerivErrF1_y(x, y float64) float64 {
+	yd := dual.Number{Real:y, Emag:1}
+	v := dual.Add(dual.Number{Real:x}, yd)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F22"},
+		want: `func DerivF22(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual_cube(dual.Sin(xd)), dual_poly(dual_sq(xd), 3))
+	return v.Emag
+}
+
+func dual_cube(x dual.Number) dual.Number {
+	return dual.Mul(x, dual_sq(x))
+}
+
+func dual_poly(x dual.Number, n int) dual.Number {
+	s := dual.Number{Real:0.0}
+	for i := 0; i <= n; i++ {
+		s = dual.Add(s, dual.PowReal(x, float64(i)))
+	}
+	return s
+}
+
+func dual_sq(x dual.Number) dual.Number {
+	return dual.Mul(x, x)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F24"},
+		want: `func DerivF24(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	t1 := dual.Sin(xd)
+	a := dual.Add(dual.Mul(t1, t1), dual.Mul(t1, dual.Inv((dual.Add(dual.Mul(xd, xd), dual.Number{Real:1})))))
+	t2 := dual.Exp(dual.Add(dual.Mul(xd, xd), dual.Number{Real:1}))
+	v := dual.Mul(dual.Mul(a, t2), t2)
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F25"},
+		want: `func DerivF25(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	t1 := dual.Mul(xd, xd)
+	v := dual.Add(dual.Add(dual.Sub(dual.Add(dual_math_Log1p(t1), dual_math_Expm1(xd)), dual_math_Log2(dual.Add(dual.Number{Real:1}, t1))), dual_math_Log10(dual.Add(dual.Number{Real:2}, xd))), dual_math_Cbrt(xd))
+	return v.Emag
+}
+
+func dual_math_Log1p(u dual.Number) dual.Number {
+	x := u.Real
+	dx := 1 / (1 + x)
+	return dual.Number{Real: math.Log1p(x), Emag: dx*u.Emag}
+}
+
+func dual_math_Expm1(u dual.Number) dual.Number {
+	x := u.Real
+	dx := math.Exp(x)
+	return dual.Number{Real: math.Expm1(x), Emag: dx*u.Emag}
+}
+
+func dual_math_Log2(u dual.Number) dual.Number {
+	x := u.Real
+	dx := 1 / (x * math.Ln2)
+	return dual.Number{Real: math.Log2(x), Emag: dx*u.Emag}
+}
+
+func dual_math_Log10(u dual.Number) dual.Number {
+	x := u.Real
+	dx := 1 / (x * math.Ln10)
+	return dual.Number{Real: math.Log10(x), Emag: dx*u.Emag}
+}
+
+func dual_math_Cbrt(u dual.Number) dual.Number {
+	x := u.Real
+	dx := 1 / (3 * math.Cbrt(x*x))
+	return dual.Number{Real: math.Cbrt(x), Emag: dx*u.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F26"},
+		want: `func DerivF26(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Mul(dual_math_Erf(xd), dual_math_Erfc(dual.Scale(1.0/2, xd))), dual_math_Gamma(dual.Add(xd, dual.Number{Real:3})))
+	return v.Emag
+}
+
+func dual_math_Erf(u dual.Number) dual.Number {
+	x := u.Real
+	dx := 2 / math.SqrtPi * math.Exp(-x*x)
+	return dual.Number{Real: math.Erf(x), Emag: dx*u.Emag}
+}
+
+func dual_math_Erfc(u dual.Number) dual.Number {
+	x := u.Real
+	dx := -2 / math.SqrtPi * math.Exp(-x*x)
+	return dual.Number{Real: math.Erfc(x), Emag: dx*u.Emag}
+}
+
+func dual_math_Gamma(u dual.Number) dual.Number {
+	x := u.Real
+	dx := math.Gamma(x) * mathext.Digamma(x)
+	return dual.Number{Real: math.Gamma(x), Emag: dx*u.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F27"},
+		want: `func DerivF27(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	l, _ := dual_math_Lgamma(dual.Add(dual.Mul(xd, xd), dual.Number{Real:1}))
+	v := dual.Add(dual_math_Atan2(xd, dual.Sub(dual.Number{Real:2}, xd)), dual.Mul(dual_math_Hypot(xd, dual.Add(dual.Scale(3, xd), dual.Number{Real:1})), l))
+	return v.Emag
+}
+
+func dual_math_Lgamma(u dual.Number) (dual.Number, int) {
+	x := u.Real
+	v, sign := math.Lgamma(x)
+	dx := mathext.Digamma(x)
+	return dual.Number{Real: v, Emag: dx*u.Emag}, sign
+}
+
+func dual_math_Atan2(u, v dual.Number) dual.Number {
+	x, y := u.Real, v.Real
+	dx := y / (x*x + y*y)
+	dy := -x / (x*x + y*y)
+	return dual.Number{Real: math.Atan2(x, y), Emag: dx*u.Emag + dy*v.Emag}
+}
+
+func dual_math_Hypot(u, v dual.Number) dual.Number {
+	x, y := u.Real, v.Real
+	dx := x / math.Hypot(x, y)
+	dy := y / math.Hypot(x, y)
+	return dual.Number{Real: math.Hypot(x, y), Emag: dx*u.Emag + dy*v.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28"},
+		want: `func DerivF28(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	relu := dual_math_Max_average(dual.Number{Real:0}, dual.Sub(dual.Mul(xd, xd), dual.Number{Real:0.5}))
+	v := dual.Add(dual.Add(dual.Mul(relu, relu), dual_math_Max_average(dual.Number{Real:0}, dual.Sub(dual.Number{Real:1}, xd))), dual_math_Min_average(xd, dual.Sub(dual.Scale(2, xd), dual.Number{Real:1})))
+	return v.Emag
+}
+
+func dual_math_Max_average(u, v dual.Number) dual.Number {
+	switch {
+	case u.Real > v.Real:
+		return u
+	case u.Real < v.Real:
+		return v
+	}
+	return dual.Scale(0.5, dual.Add(u, v))
+}
+
+func dual_math_Min_average(u, v dual.Number) dual.Number {
+	switch {
+	case u.Real > v.Real:
+		return v
+	case u.Real < v.Real:
+		return u
+	}
+	return dual.Scale(0.5, dual.Add(u, v))
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F29"},
+		want: `func DerivF29(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Add(dual.Add(dual.Mul(dual.Number{Real:math.Floor(x)}, xd), dual.Number{Real:math.Ceil(x * x)}), dual.Mul(dual.Number{Real:math.Trunc(2 * x)}, dual.Sin(xd))), dual_math_Mod(dual.Mul(xd, xd), dual.Number{Real:1.5}))
+	return v.Emag
+}
+
+func dual_math_Mod(u, v dual.Number) dual.Number {
+	x, y := u.Real, v.Real
+	dx := 1.0
+	dy := -math.Trunc(x / y)
+	return dual.Number{Real: math.Mod(x, y), Emag: dx*u.Emag + dy*v.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F30"},
+		want: `func DerivF30(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Mul(xd, dual_sigmoid(dual.Add(dual.Scale(2, xd), dual.Number{Real:1}))), dual_softplus(dual.Mul(xd, xd)))
+	return v.Emag
+}
+
+func dual_sigmoid(u dual.Number) dual.Number {
+	x := u.Real
+	dx := sigmoidDeriv(x)
+	return dual.Number{Real: sigmoid(x), Emag: dx*u.Emag}
+}
+
+func dual_softplus(u dual.Number) dual.Number {
+	x := u.Real
+	dx := sigmoid(x)
+	return dual.Number{Real: softplus(x), Emag: dx*u.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F31"},
+		want: `func DerivF31(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Mul(dual_sigmoidDeriv(xd), dual.Inv(xd))
+	return v.Emag
+}
+
+func dual_sigmoidDeriv(u dual.Number) dual.Number {
+	x := u.Real
+	dx := sigmoidDeriv2(x)
+	return dual.Number{Real: sigmoidDeriv(x), Emag: dx*u.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F32"},
+		want: `func DerivF32(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Add(dual.Scale(1e23, dual.Scale(physconst.Boltzmann, xd)), dual.Scale(physconst.Scale, dual.Sin(xd))), dual.Scale(physconst.Temperatures[1], dual.Inv((dual.Add(dual.Mul(xd, xd), dual.Number{Real:1})))))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F33"},
+		want: `func DerivF33(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Add(dual.Sin(dual.Scale(pc.Scale, xd)), dual_math_Hypot(xd, dual.Number{Real:pc.Boltzmann})), dual.PowReal(xd, pc.Temperatures[0] / 100))
+	return v.Emag
+}
+
+func dual_math_Hypot(u, v dual.Number) dual.Number {
+	x, y := u.Real, v.Real
+	dx := x / m.Hypot(x, y)
+	dy := y / m.Hypot(x, y)
+	return dual.Number{Real: m.Hypot(x, y), Emag: dx*u.Emag + dy*v.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F34"},
+		want: `func DerivF34(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	const k = 1 << 3
+	v := dual.Add(dual.Add(dual.Add(dual.Add(dual.Add(dual.Scale(1.5707963267948966, dual.Sin(xd)), dual.Scale(2.0, xd)), dual.Mul(dual.Scale(0.0, xd), xd)), dual.Scale(2.6666666666666665, dual.Exp(dual.Scale(1.0/4.0, xd)))), dual.Scale('a', xd)), dual.Scale(quarter, xd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F35", Wrt: "x"},
+		want: `func DerivF35_x(x float64, n int) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Add(dual.Mul(dual.Scale(float64(n), xd), xd), dual.Scale(float64(n << 2), dual.Inv(xd))), dual.Scale(float64(len(coeffs) - 1), dual.Cos(dual.Scale(float64(2 * n), xd))))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F39"},
+		want: `func DerivF39(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	n := 3
+	v := dual.Add(dual.Scale(float64(n / 2), xd), dual.Mul(dual.Scale(float64(n) / 2, xd), xd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F40"},
+		want: `func DerivF40(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	a := 2.0
+	b := math.Sqrt(a)
+	c := a * b
+	var ys = []float64{a, b}
+	v := dual.Add(dual.Mul(dual.Scale(c, xd), xd), dual.Scale(ys[1], xd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F36"},
+		want: `func DerivF36(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	ys := []dual.Number{xd, dual.Mul(xd, xd), dual.Number{Real:3}}
+	var zs = [2]dual.Number{1: dual.Sin(xd)}
+	v := dual.Add(dual.Add(dual.Mul(ys[1], ys[0]), ys[2]), dual.Scale(float64(len(ys)), zs[1]))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F37"},
+		want: `func DerivF37(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	s := dual.Number{Real:0.0}
+	for t := xd; t.Real < 10; t = dual.Add(t, (dual.Number{Real:1})) {
+		s = dual.Add(s, t)
+	}
+	v := s
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F38"},
+		want: `func DerivF38(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	s := dual.Number{Real:0.0}
+	for i, v := range []dual.Number{xd, dual.Scale(2, xd)} {
+		s = dual.Add(s, dual.Mul(dual.Scale(float64(i + 1), v), v))
+	}
+	v1 := s
+	return v1.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28", Subgradient: autofd.RightSubgradient},
+		want: `func DerivF28(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	relu := dual_math_Max_right(dual.Number{Real:0}, dual.Sub(dual.Mul(xd, xd), dual.Number{Real:0.5}))
+	v := dual.Add(dual.Add(dual.Mul(relu, relu), dual_math_Max_right(dual.Number{Real:0}, dual.Sub(dual.Number{Real:1}, xd))), dual_math_Min_right(xd, dual.Sub(dual.Scale(2, xd), dual.Number{Real:1})))
+	return v.Emag
+}
+
+func dual_math_Max_right(u, v dual.Number) dual.Number {
+	if d := dual.Sub(u, v); d.Real > 0 || d.Real == 0 && d.Emag > 0 {
+		return u
+	}
+	return v
+}
+
+func dual_math_Min_right(u, v dual.Number) dual.Number {
+	if d := dual.Sub(u, v); d.Real > 0 || d.Real == 0 && d.Emag > 0 {
+		return v
+	}
+	return u
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28", Subgradient: autofd.ZeroSubgradient},
+		want: `func DerivF28(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	relu := dual_math_Max_zero(dual.Number{Real:0}, dual.Sub(dual.Mul(xd, xd), dual.Number{Real:0.5}))
+	v := dual.Add(dual.Add(dual.Mul(relu, relu), dual_math_Max_zero(dual.Number{Real:0}, dual.Sub(dual.Number{Real:1}, xd))), dual_math_Min_zero(xd, dual.Sub(dual.Scale(2, xd), dual.Number{Real:1})))
+	return v.Emag
+}
+
+func dual_math_Max_zero(u, v dual.Number) dual.Number {
+	switch {
+	case u.Real > v.Real:
+		return u
+	case u.Real < v.Real:
+		return v
+	}
+	return dual.Number{Real:u.Real}
+}
+
+func dual_math_Min_zero(u, v dual.Number) dual.Number {
+	switch {
+	case u.Real > v.Real:
+		return v
+	case u.Real < v.Real:
+		return u
+	}
+	return dual.Number{Real:u.Real}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G4"},
+		want: `func DerivG4(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		return dual.Mul(dual_sq(x[0]), dual_cube(x[1]))
+	}
+	xd := make([]dual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].Emag = 1
+		grad[i] = fn(xd).Emag
+		xd[i].Emag = 0
+	}
+}
+
+func dual_sq(x dual.Number) dual.Number {
+	return dual.Mul(x, x)
+}
+
+func dual_cube(x dual.Number) dual.Number {
+	return dual.Mul(x, dual_sq(x))
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G5"},
+		want: `func DerivG5(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		return dual.Add(dual_math_Atan2(x[0], x[1]), dual.Mul(dual_math_Hypot(x[1], x[2]), dual_math_Erf(x[0])))
+	}
+	xd := make([]dual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].Emag = 1
+		grad[i] = fn(xd).Emag
+		xd[i].Emag = 0
+	}
+}
+
+func dual_math_Atan2(u, v dual.Number) dual.Number {
+	x, y := u.Real, v.Real
+	dx := y / (x*x + y*y)
+	dy := -x / (x*x + y*y)
+	return dual.Number{Real: math.Atan2(x, y), Emag: dx*u.Emag + dy*v.Emag}
+}
+
+func dual_math_Hypot(u, v dual.Number) dual.Number {
+	x, y := u.Real, v.Real
+	dx := x / math.Hypot(x, y)
+	dy := y / math.Hypot(x, y)
+	return dual.Number{Real: math.Hypot(x, y), Emag: dx*u.Emag + dy*v.Emag}
+}
+
+func dual_math_Erf(u dual.Number) dual.Number {
+	x := u.Real
+	dx := 2 / math.SqrtPi * math.Exp(-x*x)
+	return dual.Number{Real: math.Erf(x), Emag: dx*u.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G6"},
+		want: `func DerivG6(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		return dual.Add(dual.Add(dual_math_Max_average(dual.Number{Real:0}, dual.Mul(x[0], x[1])), dual_math_Min_average(x[1], x[2])), dual_math_Mod(x[2], x[3]))
+	}
+	xd := make([]dual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].Emag = 1
+		grad[i] = fn(xd).Emag
+		xd[i].Emag = 0
+	}
+}
+
+func dual_math_Mod(u, v dual.Number) dual.Number {
+	x, y := u.Real, v.Real
+	dx := 1.0
+	dy := -math.Trunc(x / y)
+	return dual.Number{Real: math.Mod(x, y), Emag: dx*u.Emag + dy*v.Emag}
+}
+
+func dual_math_Max_average(u, v dual.Number) dual.Number {
+	switch {
+	case u.Real > v.Real:
+		return u
+	case u.Real < v.Real:
+		return v
+	}
+	return dual.Scale(0.5, dual.Add(u, v))
+}
+
+func dual_math_Min_average(u, v dual.Number) dual.Number {
+	switch {
+	case u.Real > v.Real:
+		return v
+	case u.Real < v.Real:
+		return u
+	}
+	return dual.Scale(0.5, dual.Add(u, v))
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G7"},
+		want: `func DerivG7(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		return dual.Add(dual_softplus(dual.Mul(x[0], x[1])), dual.Mul(dual_sigmoid(x[2]), x[3]))
+	}
+	xd := make([]dual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].Emag = 1
+		grad[i] = fn(xd).Emag
+		xd[i].Emag = 0
+	}
+}
+
+func dual_softplus(u dual.Number) dual.Number {
+	x := u.Real
+	dx := sigmoid(x)
+	return dual.Number{Real: softplus(x), Emag: dx*u.Emag}
+}
+
+func dual_sigmoid(u dual.Number) dual.Number {
+	x := u.Real
+	dx := sigmoidDeriv(x)
+	return dual.Number{Real: sigmoid(x), Emag: dx*u.Emag}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G8"},
+		want: `func DerivG8(grad, xd []float64) {
+	if len(grad) != len(xd) {
+		panic("slice length mismatch")
+	}
+	fn1 := func(xd []dual.Number) dual.Number {
+		fn := dual.Number{Real:0.0}
+		for i, v := range xd {
+			fn = dual.Add(fn, dual.Mul(dual.Scale(float64(i + 1), v), xd[0]))
+		}
+		return fn
+	}
+	xd1 := make([]dual.Number, len(xd))
+	for i1, v1 := range xd {
+		xd1[i1].Real = v1
+	}
+	for i1 := range xd1 {
+		xd1[i1].Emag = 1
+		grad[i1] = fn1(xd1).Emag
+		xd1[i1].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G9"},
+		want: `func DerivG9(grad, n []float64) {
+	if len(grad) != len(n) {
+		panic("slice length mismatch")
+	}
+	fn := func(n []dual.Number) dual.Number {
+		return dual.Mul(dual.Mul(n[0], n[1]), dual.Exp(n[1]))
+	}
+	xd := make([]dual.Number, len(n))
+	for i, v := range n {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].Emag = 1
+		grad[i] = fn(xd).Emag
+		xd[i].Emag = 0
+	}
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "C1"},
+		want: `func DerivC1(z complex128) complex128 {
+	zd := cdual.Number{Real:z, Emag:1}
+	v := cdual.Add(cdual.Mul(zd, zd), cdual.Scale(2i, zd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "C2"},
+		want: `func DerivC2(z complex128) complex128 {
+	zd := cdual.Number{Real:z, Emag:1}
+	v := cdual.Mul(cdual.Exp(zd), cdual.Inv(cdual.Sqrt(zd)))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "C3"},
+		want: `func DerivC3(z complex128) complex128 {
+	zd := cdual.Number{Real:z, Emag:1}
+	w := cdual.Sin(zd)
+	w = cdual.Scale(2 * math.Pi, w)
+	v := cdual.Mul(w, cdual.Atan(zd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "C4", Wrt: "z"},
+		want: `func DerivC4_z(z complex128, n int) complex128 {
+	zd := cdual.Number{Real:z, Emag:1}
+	v := cdual.Sub(cdual.Pow(zd, cdual.Number{Real:complex(float64(n), 0)}), cdual_csq(zd))
+	return v.Emag
+}
+
+func cdual_csq(z cdual.Number) cdual.Number {
+	return cdual.Mul(z, z)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "S1"},
+		want: `func DerivS1(x float32) float32 {
+	xd := dual.Number{Real:float64(x), Emag:1}
+	v := dual.Add(dual.Mul(dual.Scale(3, xd), xd), dual.Inv(xd))
+	return float32(v.Emag)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "S2"},
+		want: `func DerivS2(x float32) float32 {
+	xd := dual.Number{Real:float64(x), Emag:1}
+	a := dual.Sin(xd)
+	if float32(a.Real) > 0.5 {
+		a = dual.Scale(2, a)
+	}
+	v := dual.Mul(a, dual.Exp(a))
+	return float32(v.Emag)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "S3", Wrt: "y"},
+		want: `func DerivS3_y(x, y float32) float32 {
+	yd := dual.Number{Real:float64(y), Emag:1}
+	if x > y {
+		v := dual.Scale(float64(x), yd)
+		return float32(v.Emag)
+	}
+	v := dual.Sqrt(dual.Add(dual.Number{Real:float64(x * x)}, yd))
+	return float32(v.Emag)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1[float64]"},
+		want: `func DerivP1_float64(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Add(dual.Mul(dual.Scale(3, xd), xd), dual.Sin(xd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1[float32]"},
+		want: `func DerivP1_float32(x float32) float32 {
+	xd := dual.Number{Real:float64(x), Emag:1}
+	v := dual.Add(dual.Mul(dual.Scale(3, xd), xd), dual.Sin(xd))
+	return float32(v.Emag)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P2[float64]", Wrt: "x"},
+		want: `func DerivP2_float64_x(x float64, n int) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	s := dual.Number{Real:float64(0)}
+	for i := 0; i < n; i++ {
+		s = dual.Add(s, dual.Scale(1.0/float64(i + 1), dual_psq(xd)))
+	}
+	v := s
+	return v.Emag
+}
+
+func dual_psq(x dual.Number) dual.Number {
+	return dual.Mul(x, x)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P2[float32]", Wrt: "x"},
+		want: `func DerivP2_float32_x(x float32, n int) float32 {
+	xd := dual.Number{Real:float64(x), Emag:1}
+	s := dual.Number{Real:float64(float32(0))}
+	for i := 0; i < n; i++ {
+		s = dual.Add(s, dual.Scale(1.0/float64(float32(i + 1)), dual_psq(xd)))
+	}
+	v := s
+	return float32(v.Emag)
+}
+
+func dual_psq(x dual.Number) dual.Number {
+	return dual.Mul(x, x)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P3[float64]"},
+		want: `func DerivP3_float64(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []dual.Number) dual.Number {
+		var s dual.Number
+		for i := range x {
+			s = dual.Add(s, dual_psq(x[i]))
+		}
+		return s
+	}
+	xd := make([]dual.Number, len(x))
+	for i1, v := range x {
+		xd[i1].Real = v
+	}
+	for i1 := range xd {
+		xd[i1].Emag = 1
+		grad[i1] = fn(xd).Emag
+		xd[i1].Emag = 0
+	}
+}
+
+func dual_psq(x dual.Number) dual.Number {
+	return dual.Mul(x, x)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "V1"},
+		want: `func DerivV1(x float64) float64 {
+	xd := dual.Number{Real:x, Emag:1}
+	v := dual.Mul(xd, dual.Exp(xd))
+	return v.Emag
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "V2", Wrt: "y"},
+		want: `func DerivV2_y(x float64, n int, y float64) float64 {
+	yd := dual.Number{Real:y, Emag:1}
+	v := dual.Scale(1.0/float64(n), dual.Scale(V1(x), dual_vsq(yd)))
+	return v.Emag
+}
+
+func dual_vsq(x dual.Number) dual.Number {
+	return dual.Mul(x, x)
+}
+`,
+	},
+	// second derivatives
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1"},
+		order: 2,
+		want: `func DerivF1(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Mul(xd, xd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1", Deriv: "DxF1"},
+		order: 2,
+		want: `func DxF1(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Mul(xd, xd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.F", Deriv: "DxF"},
+		order: 2,
+		want: `func DxF(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Scale(2, xd), hyperdual.Mul(hyperdual.Scale(3, xd), xd)), hyperdual.Scale(4, hyperdual.PowReal(xd, 3)))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T2.F", Deriv: "DxF"},
+		order: 2,
+		want: `func DxF(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Scale(2, xd), hyperdual.Mul(hyperdual.Scale(3, xd), xd)), hyperdual.Scale(4, hyperdual.PowReal(xd, 3)))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F2"},
+		order: 2,
+		want: `func DerivF2(y float64) (d1, d2 float64) {
+	yd := hyperdual.Number{Real:y, E1mag:1, E2mag:1}
+	v := hyperdual.Mul(yd, yd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F3"},
+		order: 2,
+		want: `func DerivF3(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Mul(hyperdual.Scale(2, xd), xd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F4"},
+		order: 2,
+		want: `func DerivF4(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Scale(2, hyperdual.Inv((hyperdual.Mul(xd, xd))))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F5"},
+		order: 2,
+		want: `func DerivF5(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Scale(2, hyperdual.Inv((hyperdual.Mul(xd, hyperdual.Scale(-1, xd)))))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F6"},
+		order: 2,
+		want: `func DerivF6(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Sub(hyperdual.Add(hyperdual.Number{Real:2}, xd), xd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F7"},
+		order: 2,
+		want: `func DerivF7(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Cos(hyperdual.Scale(6.283185307179586, xd))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F8"},
+		order: 2,
+		want: `func DerivF8(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Mul(hyperdual.Exp(xd), hyperdual.Inv(hyperdual.Sqrt(hyperdual.Add(hyperdual.PowReal(hyperdual.Sin(xd), 3), hyperdual.PowReal(hyperdual.Cos(xd), 3)))))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F9"},
+		order: 2,
+		want: `func DerivF9(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Scale(pi, xd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F10"},
+		order: 2,
+		want: `func DerivF10(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	a := hyperdual.Sin(xd)
+	v := hyperdual.Mul(a, a)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F11"},
+		order: 2,
+		want: `func DerivF11(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	var a hyperdual.Number
+	a = hyperdual.Scale(2, xd)
+	var b = hyperdual.Add(a, xd)
+	b = hyperdual.Mul(b, a)
+	v := b
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F12"},
+		order: 2,
+		want: `func DerivF12(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	const c = 3
+	a, b := xd, 2.0
+	a = hyperdual.Add(a, hyperdual.Number{Real:b})
+	v := hyperdual.Scale(b, hyperdual.Scale(c, a))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F13"},
+		order: 2,
+		want: `func DerivF13(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	if x < 0 {
+		v := hyperdual.Scale(-1, xd)
+		return v.E1mag, v.E1E2mag
+	}
+	v := xd
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F14"},
+		order: 2,
+		want: `func DerivF14(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	switch {
+	case x < 0:
+		v := hyperdual.Scale(-1, xd)
+		return v.E1mag, v.E1E2mag
+	default:
+		v := xd
+		return v.E1mag, v.E1E2mag
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F15"},
+		order: 2,
+		want: `func DerivF15(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	a := hyperdual.Mul(xd, xd)
+	switch {
+	case a.Real > 4:
+		a = hyperdual.Number{Real:4}
+	case a.Real < 1:
+		a = hyperdual.Scale(2, a)
+	}
+	if b := hyperdual.Sub(a, xd); b.Real > 0 {
+		v := b
+		return v.E1mag, v.E1E2mag
+	} else if b.Real < -1 {
+		v := hyperdual.Mul(hyperdual.Scale(-1, b), xd)
+		return v.E1mag, v.E1E2mag
+	} else {
+		v := a
+		return v.E1mag, v.E1E2mag
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F16"},
+		order: 2,
+		want: `func DerivF16(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	a := hyperdual.Sin(xd)
+	switch n := 2.0; math.Floor(a.Real * n) {
+	case -2, -1:
+		a = hyperdual.Mul(a, xd)
+		fallthrough
+	case 0:
+		a = hyperdual.Add(a, hyperdual.Number{Real:1})
+	}
+	v := a
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F17"},
+		order: 2,
+		want: `func DerivF17(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	s := hyperdual.Number{Real:0.0}
+	for i := 0; i < len(coeffs); i++ {
+		s = hyperdual.Add(s, hyperdual.Scale(coeffs[i], hyperdual.PowReal(xd, float64(i))))
+	}
+	v := s
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F18"},
+		order: 2,
+		want: `func DerivF18(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	p := hyperdual.Number{Real:1.0}
+	for i, c := range coeffs {
+		if i == 2 {
+			continue
+		}
+		p = hyperdual.Mul(p, hyperdual.Add(hyperdual.Scale(c, xd), hyperdual.Number{Real:1}))
+	}
+	v := p
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F19"},
+		order: 2,
+		want: `func DerivF19(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			v := hyperdual.Scale(-1, xd)
+			return v.E1mag, v.E1E2mag
+		}
+	}
+	v := xd
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F20"},
+		order: 2,
+		want: `func DerivF20(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	a := xd
+	n := 0
+outer:
+	for a.Real < 10 {
+		for t := 0.5; t < 2; t *= 2 {
+			a = hyperdual.Add(a, hyperdual.Scale(t, xd))
+			n++
+			if n > 20 {
+				break outer
+			}
+		}
+	}
+	v := a
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G1"},
+		order: 2,
+		want: `func DerivG1(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		return hyperdual.Add(hyperdual.Add(hyperdual.Mul(x[0], x[0]), hyperdual.Mul(hyperdual.Scale(3, x[0]), x[1])), hyperdual.Sin(x[1]))
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].E1mag = 1
+		for j := i; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i].E1mag = 0
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G2"},
+		order: 2,
+		want: `func DerivG2(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		s := hyperdual.Number{Real:0.0}
+		for i, v := range x {
+			s = hyperdual.Add(s, hyperdual.Mul(hyperdual.Scale(float64(i + 1), v), v))
+		}
+		return s
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i1, v1 := range x {
+		xd[i1].Real = v1
+	}
+	for i1 := range xd {
+		xd[i1].E1mag = 1
+		for j := i1; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i1, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i1].E1mag = 0
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G3"},
+		order: 2,
+		want: `func DerivG3(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		s := hyperdual.Number{Real:0.0}
+		for i := 0; i < len(x) - 1; i++ {
+			a := hyperdual.Sub(hyperdual.Number{Real:1}, x[i])
+			b := hyperdual.Sub(x[i + 1], hyperdual.Mul(x[i], x[i]))
+			s = hyperdual.Add(s, hyperdual.Add(hyperdual.Mul(a, a), hyperdual.Mul(hyperdual.Scale(100, b), b)))
+		}
+		return s
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i1, v := range x {
+		xd[i1].Real = v
+	}
+	for i1 := range xd {
+		xd[i1].E1mag = 1
+		for j := i1; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i1, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i1].E1mag = 0
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "x"},
+		order: 2,
+		want: `func DerivF21_x(x float64, n int, y float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Scale(y, hyperdual.PowReal(xd, float64(n)))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "y"},
+		order: 2,
+		want: `func DerivF21_y(x float64, n int, y float64) (d1, d2 float64) {
+	yd := hyperdual.Number{Real:y, E1mag:1, E2mag:1}
+	v := hyperdual.Scale(math.Pow(x, float64(n)), yd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF1", Wrt: "y"},
+		order: 2,
+		want: `func DerivErrF1_y(x, y float64) (d1, d2 float64) {
+	yd := hyperdual.Number{Real:y, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Number{Real:x}, yd)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F22"},
+		order: 2,
+		want: `func DerivF22(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual_cube(hyperdual.Sin(xd)), hyperdual_poly(hyperdual_sq(xd), 3))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_cube(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, hyperdual_sq(x))
+}
+
+func hyperdual_poly(x hyperdual.Number, n int) hyperdual.Number {
+	s := hyperdual.Number{Real:0.0}
+	for i := 0; i <= n; i++ {
+		s = hyperdual.Add(s, hyperdual.PowReal(x, float64(i)))
+	}
+	return s
+}
+
+func hyperdual_sq(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, x)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F24"},
+		order: 2,
+		want: `func DerivF24(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	t1 := hyperdual.Sin(xd)
+	a := hyperdual.Add(hyperdual.Mul(t1, t1), hyperdual.Mul(t1, hyperdual.Inv((hyperdual.Add(hyperdual.Mul(xd, xd), hyperdual.Number{Real:1})))))
+	t2 := hyperdual.Exp(hyperdual.Add(hyperdual.Mul(xd, xd), hyperdual.Number{Real:1}))
+	v := hyperdual.Mul(hyperdual.Mul(a, t2), t2)
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F25"},
+		order: 2,
+		want: `func DerivF25(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	t1 := hyperdual.Mul(xd, xd)
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Sub(hyperdual.Add(hyperdual_math_Log1p(t1), hyperdual_math_Expm1(xd)), hyperdual_math_Log2(hyperdual.Add(hyperdual.Number{Real:1}, t1))), hyperdual_math_Log10(hyperdual.Add(hyperdual.Number{Real:2}, xd))), hyperdual_math_Cbrt(xd))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Log1p(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := 1 / (1 + x)
+	dxx := -dx * dx
+	return hyperdual.Number{
+		Real:    math.Log1p(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_math_Expm1(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := math.Exp(x)
+	dxx := dx
+	return hyperdual.Number{
+		Real:    math.Expm1(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_math_Log2(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := 1 / (x * math.Ln2)
+	dxx := -dx / x
+	return hyperdual.Number{
+		Real:    math.Log2(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_math_Log10(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := 1 / (x * math.Ln10)
+	dxx := -dx / x
+	return hyperdual.Number{
+		Real:    math.Log10(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_math_Cbrt(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := 1 / (3 * math.Cbrt(x*x))
+	dxx := -2 * dx / (3 * x)
+	return hyperdual.Number{
+		Real:    math.Cbrt(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F26"},
+		order: 2,
+		want: `func DerivF26(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Mul(hyperdual_math_Erf(xd), hyperdual_math_Erfc(hyperdual.Scale(1.0/2, xd))), hyperdual_math_Gamma(hyperdual.Add(xd, hyperdual.Number{Real:3})))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Erf(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := 2 / math.SqrtPi * math.Exp(-x*x)
+	dxx := -2 * x * dx
+	return hyperdual.Number{
+		Real:    math.Erf(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_math_Erfc(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := -2 / math.SqrtPi * math.Exp(-x*x)
+	dxx := -2 * x * dx
+	return hyperdual.Number{
+		Real:    math.Erfc(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_math_Gamma(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := math.Gamma(x) * mathext.Digamma(x)
+	dxx := dx*mathext.Digamma(x) + math.Gamma(x)*mathext.Zeta(2, x)
+	return hyperdual.Number{
+		Real:    math.Gamma(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F27"},
+		order: 2,
+		want: `func DerivF27(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	l, _ := hyperdual_math_Lgamma(hyperdual.Add(hyperdual.Mul(xd, xd), hyperdual.Number{Real:1}))
+	v := hyperdual.Add(hyperdual_math_Atan2(xd, hyperdual.Sub(hyperdual.Number{Real:2}, xd)), hyperdual.Mul(hyperdual_math_Hypot(xd, hyperdual.Add(hyperdual.Scale(3, xd), hyperdual.Number{Real:1})), l))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Lgamma(u hyperdual.Number) (hyperdual.Number, int) {
+	x := u.Real
+	v, sign := math.Lgamma(x)
+	dx := mathext.Digamma(x)
+	dxx := mathext.Zeta(2, x)
+	return hyperdual.Number{
+		Real:    v,
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}, sign
+}
+
+func hyperdual_math_Atan2(u, v hyperdual.Number) hyperdual.Number {
+	x, y := u.Real, v.Real
+	dx := y / (x*x + y*y)
+	dy := -x / (x*x + y*y)
+	dxx := 2 * dx * dy
+	dxy := dy*dy - dx*dx
+	dyy := -2 * dx * dy
+	return hyperdual.Number{
+		Real:    math.Atan2(x, y),
+		E1mag:   dx*u.E1mag + dy*v.E1mag,
+		E2mag:   dx*u.E2mag + dy*v.E2mag,
+		E1E2mag: dx*u.E1E2mag + dy*v.E1E2mag + dxx*u.E1mag*u.E2mag + dxy*(u.E1mag*v.E2mag+v.E1mag*u.E2mag) + dyy*v.E1mag*v.E2mag,
+	}
+}
+
+func hyperdual_math_Hypot(u, v hyperdual.Number) hyperdual.Number {
+	x, y := u.Real, v.Real
+	dx := x / math.Hypot(x, y)
+	dy := y / math.Hypot(x, y)
+	dxx := dy * dy / math.Hypot(x, y)
+	dxy := -dx * dy / math.Hypot(x, y)
+	dyy := dx * dx / math.Hypot(x, y)
+	return hyperdual.Number{
+		Real:    math.Hypot(x, y),
+		E1mag:   dx*u.E1mag + dy*v.E1mag,
+		E2mag:   dx*u.E2mag + dy*v.E2mag,
+		E1E2mag: dx*u.E1E2mag + dy*v.E1E2mag + dxx*u.E1mag*u.E2mag + dxy*(u.E1mag*v.E2mag+v.E1mag*u.E2mag) + dyy*v.E1mag*v.E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28"},
+		order: 2,
+		want: `func DerivF28(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	relu := hyperdual_math_Max_average(hyperdual.Number{Real:0}, hyperdual.Sub(hyperdual.Mul(xd, xd), hyperdual.Number{Real:0.5}))
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Mul(relu, relu), hyperdual_math_Max_average(hyperdual.Number{Real:0}, hyperdual.Sub(hyperdual.Number{Real:1}, xd))), hyperdual_math_Min_average(xd, hyperdual.Sub(hyperdual.Scale(2, xd), hyperdual.Number{Real:1})))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Max_average(u, v hyperdual.Number) hyperdual.Number {
+	switch {
+	case u.Real > v.Real:
+		return u
+	case u.Real < v.Real:
+		return v
+	}
+	return hyperdual.Scale(0.5, hyperdual.Add(u, v))
+}
+
+func hyperdual_math_Min_average(u, v hyperdual.Number) hyperdual.Number {
+	switch {
+	case u.Real > v.Real:
+		return v
+	case u.Real < v.Real:
+		return u
+	}
+	return hyperdual.Scale(0.5, hyperdual.Add(u, v))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F29"},
+		order: 2,
+		want: `func DerivF29(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Add(hyperdual.Mul(hyperdual.Number{Real:math.Floor(x)}, xd), hyperdual.Number{Real:math.Ceil(x * x)}), hyperdual.Mul(hyperdual.Number{Real:math.Trunc(2 * x)}, hyperdual.Sin(xd))), hyperdual_math_Mod(hyperdual.Mul(xd, xd), hyperdual.Number{Real:1.5}))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Mod(u, v hyperdual.Number) hyperdual.Number {
+	x, y := u.Real, v.Real
+	dx := 1.0
+	dy := -math.Trunc(x / y)
+	return hyperdual.Number{
+		Real:    math.Mod(x, y),
+		E1mag:   dx*u.E1mag + dy*v.E1mag,
+		E2mag:   dx*u.E2mag + dy*v.E2mag,
+		E1E2mag: dx*u.E1E2mag + dy*v.E1E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F30"},
+		order: 2,
+		want: `func DerivF30(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Mul(xd, hyperdual_sigmoid(hyperdual.Add(hyperdual.Scale(2, xd), hyperdual.Number{Real:1}))), hyperdual_softplus(hyperdual.Mul(xd, xd)))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_sigmoid(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := sigmoidDeriv(x)
+	dxx := sigmoidDeriv2(x)
+	return hyperdual.Number{
+		Real:    sigmoid(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_softplus(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := sigmoid(x)
+	dxx := sigmoidDeriv(x)
+	return hyperdual.Number{
+		Real:    softplus(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F32"},
+		order: 2,
+		want: `func DerivF32(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Scale(1e23, hyperdual.Scale(physconst.Boltzmann, xd)), hyperdual.Scale(physconst.Scale, hyperdual.Sin(xd))), hyperdual.Scale(physconst.Temperatures[1], hyperdual.Inv((hyperdual.Add(hyperdual.Mul(xd, xd), hyperdual.Number{Real:1})))))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F33"},
+		order: 2,
+		want: `func DerivF33(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Sin(hyperdual.Scale(pc.Scale, xd)), hyperdual_math_Hypot(xd, hyperdual.Number{Real:pc.Boltzmann})), hyperdual.PowReal(xd, pc.Temperatures[0] / 100))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Hypot(u, v hyperdual.Number) hyperdual.Number {
+	x, y := u.Real, v.Real
+	dx := x / m.Hypot(x, y)
+	dy := y / m.Hypot(x, y)
+	dxx := dy * dy / m.Hypot(x, y)
+	dxy := -dx * dy / m.Hypot(x, y)
+	dyy := dx * dx / m.Hypot(x, y)
+	return hyperdual.Number{
+		Real:    m.Hypot(x, y),
+		E1mag:   dx*u.E1mag + dy*v.E1mag,
+		E2mag:   dx*u.E2mag + dy*v.E2mag,
+		E1E2mag: dx*u.E1E2mag + dy*v.E1E2mag + dxx*u.E1mag*u.E2mag + dxy*(u.E1mag*v.E2mag+v.E1mag*u.E2mag) + dyy*v.E1mag*v.E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F34"},
+		order: 2,
+		want: `func DerivF34(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	const k = 1 << 3
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Add(hyperdual.Add(hyperdual.Add(hyperdual.Scale(1.5707963267948966, hyperdual.Sin(xd)), hyperdual.Scale(2.0, xd)), hyperdual.Mul(hyperdual.Scale(0.0, xd), xd)), hyperdual.Scale(2.6666666666666665, hyperdual.Exp(hyperdual.Scale(1.0/4.0, xd)))), hyperdual.Scale('a', xd)), hyperdual.Scale(quarter, xd))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F35", Wrt: "x"},
+		order: 2,
+		want: `func DerivF35_x(x float64, n int) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Mul(hyperdual.Scale(float64(n), xd), xd), hyperdual.Scale(float64(n << 2), hyperdual.Inv(xd))), hyperdual.Scale(float64(len(coeffs) - 1), hyperdual.Cos(hyperdual.Scale(float64(2 * n), xd))))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F36"},
+		order: 2,
+		want: `func DerivF36(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	ys := []hyperdual.Number{xd, hyperdual.Mul(xd, xd), hyperdual.Number{Real:3}}
+	var zs = [2]hyperdual.Number{1: hyperdual.Sin(xd)}
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Mul(ys[1], ys[0]), ys[2]), hyperdual.Scale(float64(len(ys)), zs[1]))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F37"},
+		order: 2,
+		want: `func DerivF37(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	s := hyperdual.Number{Real:0.0}
+	for t := xd; t.Real < 10; t = hyperdual.Add(t, (hyperdual.Number{Real:1})) {
+		s = hyperdual.Add(s, t)
+	}
+	v := s
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F38"},
+		order: 2,
+		want: `func DerivF38(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	s := hyperdual.Number{Real:0.0}
+	for i, v := range []hyperdual.Number{xd, hyperdual.Scale(2, xd)} {
+		s = hyperdual.Add(s, hyperdual.Mul(hyperdual.Scale(float64(i + 1), v), v))
+	}
+	v1 := s
+	return v1.E1mag, v1.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28", Subgradient: autofd.LeftSubgradient},
+		order: 2,
+		want: `func DerivF28(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	relu := hyperdual_math_Max_left(hyperdual.Number{Real:0}, hyperdual.Sub(hyperdual.Mul(xd, xd), hyperdual.Number{Real:0.5}))
+	v := hyperdual.Add(hyperdual.Add(hyperdual.Mul(relu, relu), hyperdual_math_Max_left(hyperdual.Number{Real:0}, hyperdual.Sub(hyperdual.Number{Real:1}, xd))), hyperdual_math_Min_left(xd, hyperdual.Sub(hyperdual.Scale(2, xd), hyperdual.Number{Real:1})))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_math_Max_left(u, v hyperdual.Number) hyperdual.Number {
+	if d := hyperdual.Sub(u, v); d.Real > 0 || d.Real == 0 && (d.E1mag < 0 || d.E1mag == 0 && d.E1E2mag > 0) {
+		return u
+	}
+	return v
+}
+
+func hyperdual_math_Min_left(u, v hyperdual.Number) hyperdual.Number {
+	if d := hyperdual.Sub(u, v); d.Real > 0 || d.Real == 0 && (d.E1mag < 0 || d.E1mag == 0 && d.E1E2mag > 0) {
+		return v
+	}
+	return u
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G4"},
+		order: 2,
+		want: `func DerivG4(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		return hyperdual.Mul(hyperdual_sq(x[0]), hyperdual_cube(x[1]))
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].E1mag = 1
+		for j := i; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i].E1mag = 0
+	}
+}
+
+func hyperdual_sq(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, x)
+}
+
+func hyperdual_cube(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, hyperdual_sq(x))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G5"},
+		order: 2,
+		want: `func DerivG5(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		return hyperdual.Add(hyperdual_math_Atan2(x[0], x[1]), hyperdual.Mul(hyperdual_math_Hypot(x[1], x[2]), hyperdual_math_Erf(x[0])))
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].E1mag = 1
+		for j := i; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i].E1mag = 0
+	}
+}
+
+func hyperdual_math_Atan2(u, v hyperdual.Number) hyperdual.Number {
+	x, y := u.Real, v.Real
+	dx := y / (x*x + y*y)
+	dy := -x / (x*x + y*y)
+	dxx := 2 * dx * dy
+	dxy := dy*dy - dx*dx
+	dyy := -2 * dx * dy
+	return hyperdual.Number{
+		Real:    math.Atan2(x, y),
+		E1mag:   dx*u.E1mag + dy*v.E1mag,
+		E2mag:   dx*u.E2mag + dy*v.E2mag,
+		E1E2mag: dx*u.E1E2mag + dy*v.E1E2mag + dxx*u.E1mag*u.E2mag + dxy*(u.E1mag*v.E2mag+v.E1mag*u.E2mag) + dyy*v.E1mag*v.E2mag,
+	}
+}
+
+func hyperdual_math_Hypot(u, v hyperdual.Number) hyperdual.Number {
+	x, y := u.Real, v.Real
+	dx := x / math.Hypot(x, y)
+	dy := y / math.Hypot(x, y)
+	dxx := dy * dy / math.Hypot(x, y)
+	dxy := -dx * dy / math.Hypot(x, y)
+	dyy := dx * dx / math.Hypot(x, y)
+	return hyperdual.Number{
+		Real:    math.Hypot(x, y),
+		E1mag:   dx*u.E1mag + dy*v.E1mag,
+		E2mag:   dx*u.E2mag + dy*v.E2mag,
+		E1E2mag: dx*u.E1E2mag + dy*v.E1E2mag + dxx*u.E1mag*u.E2mag + dxy*(u.E1mag*v.E2mag+v.E1mag*u.E2mag) + dyy*v.E1mag*v.E2mag,
+	}
+}
+
+func hyperdual_math_Erf(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := 2 / math.SqrtPi * math.Exp(-x*x)
+	dxx := -2 * x * dx
+	return hyperdual.Number{
+		Real:    math.Erf(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G6"},
+		order: 2,
+		want: `func DerivG6(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		return hyperdual.Add(hyperdual.Add(hyperdual_math_Max_average(hyperdual.Number{Real:0}, hyperdual.Mul(x[0], x[1])), hyperdual_math_Min_average(x[1], x[2])), hyperdual_math_Mod(x[2], x[3]))
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].E1mag = 1
+		for j := i; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i].E1mag = 0
+	}
+}
+
+func hyperdual_math_Mod(u, v hyperdual.Number) hyperdual.Number {
+	x, y := u.Real, v.Real
+	dx := 1.0
+	dy := -math.Trunc(x / y)
+	return hyperdual.Number{
+		Real:    math.Mod(x, y),
+		E1mag:   dx*u.E1mag + dy*v.E1mag,
+		E2mag:   dx*u.E2mag + dy*v.E2mag,
+		E1E2mag: dx*u.E1E2mag + dy*v.E1E2mag,
+	}
+}
+
+func hyperdual_math_Max_average(u, v hyperdual.Number) hyperdual.Number {
+	switch {
+	case u.Real > v.Real:
+		return u
+	case u.Real < v.Real:
+		return v
+	}
+	return hyperdual.Scale(0.5, hyperdual.Add(u, v))
+}
+
+func hyperdual_math_Min_average(u, v hyperdual.Number) hyperdual.Number {
+	switch {
+	case u.Real > v.Real:
+		return v
+	case u.Real < v.Real:
+		return u
+	}
+	return hyperdual.Scale(0.5, hyperdual.Add(u, v))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G7"},
+		order: 2,
+		want: `func DerivG7(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		return hyperdual.Add(hyperdual_softplus(hyperdual.Mul(x[0], x[1])), hyperdual.Mul(hyperdual_sigmoid(x[2]), x[3]))
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i, v := range x {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].E1mag = 1
+		for j := i; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i].E1mag = 0
+	}
+}
+
+func hyperdual_softplus(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := sigmoid(x)
+	dxx := sigmoidDeriv(x)
+	return hyperdual.Number{
+		Real:    softplus(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+
+func hyperdual_sigmoid(u hyperdual.Number) hyperdual.Number {
+	x := u.Real
+	dx := sigmoidDeriv(x)
+	dxx := sigmoidDeriv2(x)
+	return hyperdual.Number{
+		Real:    sigmoid(x),
+		E1mag:   dx*u.E1mag,
+		E2mag:   dx*u.E2mag,
+		E1E2mag: dx*u.E1E2mag + dxx*u.E1mag*u.E2mag,
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G9"},
+		order: 2,
+		want: `func DerivG9(hess *mat.SymDense, n []float64) {
+	if n1, _ := hess.Dims(); n1 != len(n) {
+		panic("matrix size mismatch")
+	}
+	fn := func(n []hyperdual.Number) hyperdual.Number {
+		return hyperdual.Mul(hyperdual.Mul(n[0], n[1]), hyperdual.Exp(n[1]))
+	}
+	xd := make([]hyperdual.Number, len(n))
+	for i, v := range n {
+		xd[i].Real = v
+	}
+	for i := range xd {
+		xd[i].E1mag = 1
+		for j := i; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i].E1mag = 0
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "S1"},
+		order: 2,
+		want: `func DerivS1(x float32) (d1, d2 float32) {
+	xd := hyperdual.Number{Real:float64(x), E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Mul(hyperdual.Scale(3, xd), xd), hyperdual.Inv(xd))
+	return float32(v.E1mag), float32(v.E1E2mag)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "S2"},
+		order: 2,
+		want: `func DerivS2(x float32) (d1, d2 float32) {
+	xd := hyperdual.Number{Real:float64(x), E1mag:1, E2mag:1}
+	a := hyperdual.Sin(xd)
+	if float32(a.Real) > 0.5 {
+		a = hyperdual.Scale(2, a)
+	}
+	v := hyperdual.Mul(a, hyperdual.Exp(a))
+	return float32(v.E1mag), float32(v.E1E2mag)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "S3", Wrt: "y"},
+		order: 2,
+		want: `func DerivS3_y(x, y float32) (d1, d2 float32) {
+	yd := hyperdual.Number{Real:float64(y), E1mag:1, E2mag:1}
+	if x > y {
+		v := hyperdual.Scale(float64(x), yd)
+		return float32(v.E1mag), float32(v.E1E2mag)
+	}
+	v := hyperdual.Sqrt(hyperdual.Add(hyperdual.Number{Real:float64(x * x)}, yd))
+	return float32(v.E1mag), float32(v.E1E2mag)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1[float64]"},
+		order: 2,
+		want: `func DerivP1_float64(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Mul(hyperdual.Scale(3, xd), xd), hyperdual.Sin(xd))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1[float32]"},
+		order: 2,
+		want: `func DerivP1_float32(x float32) (d1, d2 float32) {
+	xd := hyperdual.Number{Real:float64(x), E1mag:1, E2mag:1}
+	v := hyperdual.Add(hyperdual.Mul(hyperdual.Scale(3, xd), xd), hyperdual.Sin(xd))
+	return float32(v.E1mag), float32(v.E1E2mag)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P2[float64]", Wrt: "x"},
+		order: 2,
+		want: `func DerivP2_float64_x(x float64, n int) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	s := hyperdual.Number{Real:float64(0)}
+	for i := 0; i < n; i++ {
+		s = hyperdual.Add(s, hyperdual.Scale(1.0/float64(i + 1), hyperdual_psq(xd)))
+	}
+	v := s
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_psq(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, x)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P2[float32]", Wrt: "x"},
+		order: 2,
+		want: `func DerivP2_float32_x(x float32, n int) (d1, d2 float32) {
+	xd := hyperdual.Number{Real:float64(x), E1mag:1, E2mag:1}
+	s := hyperdual.Number{Real:float64(float32(0))}
+	for i := 0; i < n; i++ {
+		s = hyperdual.Add(s, hyperdual.Scale(1.0/float64(float32(i + 1)), hyperdual_psq(xd)))
+	}
+	v := s
+	return float32(v.E1mag), float32(v.E1E2mag)
+}
+
+func hyperdual_psq(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, x)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P3[float64]"},
+		order: 2,
+		want: `func DerivP3_float64(hess *mat.SymDense, x []float64) {
+	if n, _ := hess.Dims(); n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(x []hyperdual.Number) hyperdual.Number {
+		var s hyperdual.Number
+		for i := range x {
+			s = hyperdual.Add(s, hyperdual_psq(x[i]))
+		}
+		return s
+	}
+	xd := make([]hyperdual.Number, len(x))
+	for i1, v := range x {
+		xd[i1].Real = v
+	}
+	for i1 := range xd {
+		xd[i1].E1mag = 1
+		for j := i1; j < len(xd); j++ {
+			xd[j].E2mag = 1
+			hess.SetSym(i1, j, fn(xd).E1E2mag)
+			xd[j].E2mag = 0
+		}
+		xd[i1].E1mag = 0
+	}
+}
+
+func hyperdual_psq(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, x)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "V1"},
+		order: 2,
+		want: `func DerivV1(x float64) (d1, d2 float64) {
+	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+	v := hyperdual.Mul(xd, hyperdual.Exp(xd))
+	return v.E1mag, v.E1E2mag
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "V2", Wrt: "y"},
+		order: 2,
+		want: `func DerivV2_y(x float64, n int, y float64) (d1, d2 float64) {
+	yd := hyperdual.Number{Real:y, E1mag:1, E2mag:1}
+	v := hyperdual.Scale(1.0/float64(n), hyperdual.Scale(V1(x), hyperdual_vsq(yd)))
+	return v.E1mag, v.E1E2mag
+}
+
+func hyperdual_vsq(x hyperdual.Number) hyperdual.Number {
+	return hyperdual.Mul(x, x)
+}
+`,
+	},
+	// higher order derivatives
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1"},
+		order: 3,
+		want: `func DerivF1(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Mul(xd, xd)
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.F"},
+		order: 3,
+		want: `func DerivT1_F(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Add(taylor.Add(taylor.Scale(2, xd), taylor.Mul(taylor.Scale(3, xd), xd)), taylor.Scale(4, taylor.PowReal(xd, 3)))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F7"},
+		order: 3,
+		want: `func DerivF7(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Cos(taylor.Scale(6.283185307179586, xd))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F11"},
+		order: 3,
+		want: `func DerivF11(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	var a = taylor.Number{0}
+	a = taylor.Scale(2, xd)
+	var b = taylor.Add(a, xd)
+	b = taylor.Mul(b, a)
+	v := b
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F16"},
+		order: 3,
+		want: `func DerivF16(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	a := taylor.Sin(xd)
+	switch n := 2.0; math.Floor(a[0] * n) {
+	case -2, -1:
+		a = taylor.Mul(a, xd)
+		fallthrough
+	case 0:
+		a = taylor.Add(a, taylor.Number{1})
+	}
+	v := a
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "y"},
+		order: 3,
+		want: `func DerivF21_y(x float64, n int, y float64) (d1, d2, d3 float64) {
+	yd := taylor.Var(y, 3)
+	v := taylor.Scale(math.Pow(x, float64(n)), yd)
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F22"},
+		order: 3,
+		want: `func DerivF22(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Add(taylor_cube(taylor.Sin(xd)), taylor_poly(taylor_sq(xd), 3))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+
+func taylor_cube(x taylor.Number) taylor.Number {
+	return taylor.Mul(x, taylor_sq(x))
+}
+
+func taylor_poly(x taylor.Number, n int) taylor.Number {
+	s := taylor.Number{0.0}
+	for i := 0; i <= n; i++ {
+		s = taylor.Add(s, taylor.PowReal(x, float64(i)))
+	}
+	return s
+}
+
+func taylor_sq(x taylor.Number) taylor.Number {
+	return taylor.Mul(x, x)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1"},
+		order: 4,
+		want: `func DerivF1(x float64) (d1, d2, d3, d4 float64) {
+	xd := taylor.Var(x, 4)
+	v := taylor.Mul(xd, xd)
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3), v.Deriv(4)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F25"},
+		order: 3,
+		want: `func DerivF25(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	t1 := taylor.Mul(xd, xd)
+	v := taylor.Add(taylor.Add(taylor.Sub(taylor.Add(taylor.Log1p(t1), taylor.Expm1(xd)), taylor.Log2(taylor.Add(taylor.Number{1}, t1))), taylor.Log10(taylor.Add(taylor.Number{2}, xd))), taylor.Cbrt(xd))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28"},
+		order: 3,
+		want: `func DerivF28(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	relu := taylor_math_Max_average(taylor.Number{0}, taylor.Sub(taylor.Mul(xd, xd), taylor.Number{0.5}))
+	v := taylor.Add(taylor.Add(taylor.Mul(relu, relu), taylor_math_Max_average(taylor.Number{0}, taylor.Sub(taylor.Number{1}, xd))), taylor_math_Min_average(xd, taylor.Sub(taylor.Scale(2, xd), taylor.Number{1})))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+
+func taylor_math_Max_average(u, v taylor.Number) taylor.Number {
+	switch {
+	case u[0] > v[0]:
+		return u
+	case u[0] < v[0]:
+		return v
+	}
+	return taylor.Scale(0.5, taylor.Add(u, v))
+}
+
+func taylor_math_Min_average(u, v taylor.Number) taylor.Number {
+	switch {
+	case u[0] > v[0]:
+		return v
+	case u[0] < v[0]:
+		return u
+	}
+	return taylor.Scale(0.5, taylor.Add(u, v))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28", Subgradient: autofd.LeftSubgradient},
+		order: 3,
+		want: `func DerivF28(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	relu := taylor_math_Max_left(taylor.Number{0}, taylor.Sub(taylor.Mul(xd, xd), taylor.Number{0.5}))
+	v := taylor.Add(taylor.Add(taylor.Mul(relu, relu), taylor_math_Max_left(taylor.Number{0}, taylor.Sub(taylor.Number{1}, xd))), taylor_math_Min_left(xd, taylor.Sub(taylor.Scale(2, xd), taylor.Number{1})))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+
+func taylor_math_Max_left(u, v taylor.Number) taylor.Number {
+	for k, d := range taylor.Sub(u, v) {
+		if k%2 == 1 {
+			d = -d
+		}
+		switch {
+		case d > 0:
+			return u
+		case d < 0:
+			return v
+		}
+	}
+	return u
+}
+
+func taylor_math_Min_left(u, v taylor.Number) taylor.Number {
+	for k, d := range taylor.Sub(u, v) {
+		if k%2 == 1 {
+			d = -d
+		}
+		switch {
+		case d > 0:
+			return v
+		case d < 0:
+			return u
+		}
+	}
+	return v
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F29"},
+		order: 3,
+		want: `func DerivF29(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Add(taylor.Add(taylor.Add(taylor.Mul(taylor.Number{math.Floor(x)}, xd), taylor.Number{math.Ceil(x * x)}), taylor.Mul(taylor.Number{math.Trunc(2 * x)}, taylor.Sin(xd))), taylor.Mod(taylor.Mul(xd, xd), taylor.Number{1.5}))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F32"},
+		order: 3,
+		want: `func DerivF32(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Add(taylor.Add(taylor.Scale(1e23, taylor.Scale(physconst.Boltzmann, xd)), taylor.Scale(physconst.Scale, taylor.Sin(xd))), taylor.Scale(physconst.Temperatures[1], taylor.Inv((taylor.Add(taylor.Mul(xd, xd), taylor.Number{1})))))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F34"},
+		order: 3,
+		want: `func DerivF34(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	const k = 1 << 3
+	v := taylor.Add(taylor.Add(taylor.Add(taylor.Add(taylor.Add(taylor.Scale(1.5707963267948966, taylor.Sin(xd)), taylor.Scale(2.0, xd)), taylor.Mul(taylor.Scale(0.0, xd), xd)), taylor.Scale(2.6666666666666665, taylor.Exp(taylor.Scale(1.0/4.0, xd)))), taylor.Scale('a', xd)), taylor.Scale(quarter, xd))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F35", Wrt: "x"},
+		order: 3,
+		want: `func DerivF35_x(x float64, n int) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	v := taylor.Add(taylor.Add(taylor.Mul(taylor.Scale(float64(n), xd), xd), taylor.Scale(float64(n << 2), taylor.Inv(xd))), taylor.Scale(float64(len(coeffs) - 1), taylor.Cos(taylor.Scale(float64(2 * n), xd))))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F36"},
+		order: 3,
+		want: `func DerivF36(x float64) (d1, d2, d3 float64) {
+	xd := taylor.Var(x, 3)
+	ys := []taylor.Number{xd, taylor.Mul(xd, xd), taylor.Number{3}}
+	var zs = [2]taylor.Number{1: taylor.Sin(xd)}
+	v := taylor.Add(taylor.Add(taylor.Mul(ys[1], ys[0]), ys[2]), taylor.Scale(float64(len(ys)), zs[1]))
+	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+}
+`,
+	},
+	// reverse mode
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G1", Mode: autofd.Reverse},
+		want: `func DerivG1(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []adjoint.Number) adjoint.Number {
+		return adjoint.Add(adjoint.Add(adjoint.Mul(x[0], x[0]), adjoint.Mul(adjoint.Scale(3, x[0]), x[1])), adjoint.Sin(x[1]))
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(x))
+	for i, v := range x {
+		xd[i] = tape.Var(v)
+	}
+	tape.Gradient(grad, fn(xd))
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G4", Mode: autofd.Reverse},
+		want: `func DerivG4(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []adjoint.Number) adjoint.Number {
+		return adjoint.Mul(adjoint_sq(x[0]), adjoint_cube(x[1]))
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(x))
+	for i, v := range x {
+		xd[i] = tape.Var(v)
+	}
+	tape.Gradient(grad, fn(xd))
+}
+
+func adjoint_sq(x adjoint.Number) adjoint.Number {
+	return adjoint.Mul(x, x)
+}
+
+func adjoint_cube(x adjoint.Number) adjoint.Number {
+	return adjoint.Mul(x, adjoint_sq(x))
+}
+`,
+	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1"},
-		want: `func DerivF1(x float64) float64 {
-	v := dual.Mul(dual.Number{Real:x, Emag:1}, dual.Number{Real:x, Emag:1})
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G5", Mode: autofd.Reverse},
+		want: `func DerivG5(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []adjoint.Number) adjoint.Number {
+		return adjoint.Add(adjoint.Atan2(x[0], x[1]), adjoint.Mul(adjoint.Hypot(x[1], x[2]), adjoint.Erf(x[0])))
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(x))
+	for i, v := range x {
+		xd[i] = tape.Var(v)
+	}
+	tape.Gradient(grad, fn(xd))
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1", Deriv: "DxF1"},
-		want: `func DxF1(x float64) float64 {
-	v := dual.Mul(dual.Number{Real:x, Emag:1}, dual.Number{Real:x, Emag:1})
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G6", Mode: autofd.Reverse},
+		want: `func DerivG6(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []adjoint.Number) adjoint.Number {
+		return adjoint.Add(adjoint.Add(adjoint_math_Max_average(adjoint.Number{Real:0}, adjoint.Mul(x[0], x[1])), adjoint_math_Min_average(x[1], x[2])), adjoint.Mod(x[2], x[3]))
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(x))
+	for i, v := range x {
+		xd[i] = tape.Var(v)
+	}
+	tape.Gradient(grad, fn(xd))
+}
+
+func adjoint_math_Max_average(u, v adjoint.Number) adjoint.Number {
+	switch {
+	case u.Real > v.Real:
+		return u
+	case u.Real < v.Real:
+		return v
+	}
+	return adjoint.Scale(0.5, adjoint.Add(u, v))
+}
+
+func adjoint_math_Min_average(u, v adjoint.Number) adjoint.Number {
+	switch {
+	case u.Real > v.Real:
+		return v
+	case u.Real < v.Real:
+		return u
+	}
+	return adjoint.Scale(0.5, adjoint.Add(u, v))
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.F", Deriv: "DxF"},
-		want: `func DxF(x float64) float64 {
-	v := dual.Add(dual.Add(dual.Mul(dual.Number{Real:2}, dual.Number{Real:x, Emag:1}), dual.Mul(dual.Mul(dual.Number{Real:3}, dual.Number{Real:x, Emag:1}), dual.Number{Real:x, Emag:1})), dual.Mul(dual.Number{Real:4}, dual.Pow(dual.Number{Real:x, Emag:1}, dual.Number{Real:3})))
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G7", Mode: autofd.Reverse},
+		want: `func DerivG7(grad, x []float64) {
+	if len(grad) != len(x) {
+		panic("slice length mismatch")
+	}
+	fn := func(x []adjoint.Number) adjoint.Number {
+		return adjoint.Add(adjoint_softplus(adjoint.Mul(x[0], x[1])), adjoint.Mul(adjoint_sigmoid(x[2]), x[3]))
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(x))
+	for i, v := range x {
+		xd[i] = tape.Var(v)
+	}
+	tape.Gradient(grad, fn(xd))
+}
+
+func adjoint_softplus(u adjoint.Number) adjoint.Number {
+	x := u.Real
+	dx := sigmoid(x)
+	w := adjoint.Scale(dx, u)
+	w.Real = softplus(x)
+	return w
+}
+
+func adjoint_sigmoid(u adjoint.Number) adjoint.Number {
+	x := u.Real
+	dx := sigmoidDeriv(x)
+	w := adjoint.Scale(dx, u)
+	w.Real = sigmoid(x)
+	return w
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T2.F", Deriv: "DxF"},
-		want: `func DxF(x float64) float64 {
-	v := dual.Add(dual.Add(dual.Mul(dual.Number{Real:2}, dual.Number{Real:x, Emag:1}), dual.Mul(dual.Mul(dual.Number{Real:3}, dual.Number{Real:x, Emag:1}), dual.Number{Real:x, Emag:1})), dual.Mul(dual.Number{Real:4}, dual.Pow(dual.Number{Real:x, Emag:1}, dual.Number{Real:3})))
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G10", Mode: autofd.Reverse},
+		want: `func DerivG10(grad, tape []float64) {
+	if len(grad) != len(tape) {
+		panic("slice length mismatch")
+	}
+	fn := func(tape []adjoint.Number) adjoint.Number {
+		row := adjoint.Mul(tape[0], tape[1])
+		return adjoint.Add(row, adjoint.Sin(tape[1]))
+	}
+	var tape1 adjoint.Tape
+	xd := make([]adjoint.Number, len(tape))
+	for i, v := range tape {
+		xd[i] = tape1.Var(v)
+	}
+	tape1.Gradient(grad, fn(xd))
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F2"},
-		want: `func DerivF2(y float64) float64 {
-	v := dual.Mul(dual.Number{Real:y, Emag:1}, dual.Number{Real:y, Emag:1})
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "J1", Mode: autofd.Reverse},
+		want: `func DerivJ1(jac *mat.Dense, x []float64) {
+	m, n := jac.Dims()
+	if n != len(x) {
+		panic("matrix size mismatch")
+	}
+	fn := func(dst, x []adjoint.Number) {
+		dst[0] = adjoint.Mul(x[0], x[1])
+		dst[1] = adjoint.Add(adjoint.Sin(x[0]), adjoint.Mul(x[1], x[1]))
+		dst[2] = adjoint.Exp(adjoint.Sub(x[0], x[1]))
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(x))
+	for i, v := range x {
+		xd[i] = tape.Var(v)
+	}
+	yd := make([]adjoint.Number, m)
+	fn(yd, xd)
+	row := make([]float64, n)
+	for i, v := range yd {
+		tape.Gradient(row, v)
+		jac.SetRow(i, row)
+	}
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F3"},
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "J3", Mode: autofd.Reverse},
+		want: `func DerivJ3(jac *mat.Dense, n []float64) {
+	m1, n1 := jac.Dims()
+	if n1 != len(n) {
+		panic("matrix size mismatch")
+	}
+	fn := func(dst, n []adjoint.Number) {
+		m := float64(len(n))
+		dst[0] = adjoint.Mul(adjoint.Scale(m, n[0]), n[1])
+		dst[1] = adjoint.Sub(adjoint.Sin(n[0]), n[1])
+	}
+	var tape adjoint.Tape
+	xd := make([]adjoint.Number, len(n))
+	for i, v := range n {
+		xd[i] = tape.Var(v)
+	}
+	yd := make([]adjoint.Number, m1)
+	fn(yd, xd)
+	row := make([]float64, n1)
+	for i, v := range yd {
+		tape.Gradient(row, v)
+		jac.SetRow(i, row)
+	}
+}
+`,
+	},
+	// symbolic mode
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F3", Mode: autofd.Symbolic},
 		want: `func DerivF3(x float64) float64 {
-	v := dual.Mul(dual.Mul(dual.Number{Real:2}, dual.Number{Real:x, Emag:1}), dual.Number{Real:x, Emag:1})
-	return v.Emag
+	return 4 * x
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F4"},
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F4", Mode: autofd.Symbolic},
 		want: `func DerivF4(x float64) float64 {
-	v := dual.Mul(dual.Number{Real:2}, dual.Inv((dual.Mul(dual.Number{Real:x, Emag:1}, dual.Number{Real:x, Emag:1}))))
-	return v.Emag
+	return -4 * x / (x * x * x * x)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F5"},
-		want: `func DerivF5(x float64) float64 {
-	v := dual.Mul(dual.Number{Real:2}, dual.Inv((dual.Mul(dual.Number{Real:x, Emag:1}, dual.Mul(dual.Number{Real:-1}, dual.Number{Real:x, Emag:1})))))
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F7", Mode: autofd.Symbolic},
+		want: `func DerivF7(x float64) float64 {
+	return -6.283185307179586 * math.Sin(6.283185307179586*x)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F6"},
-		want: `func DerivF6(x float64) float64 {
-	v := dual.Sub(dual.Add(dual.Number{Real:2}, dual.Number{Real:x, Emag:1}), dual.Number{Real:x, Emag:1})
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F10", Mode: autofd.Symbolic},
+		want: `func DerivF10(x float64) float64 {
+	return 2 * math.Cos(x) * math.Sin(x)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F7"},
-		want: `func DerivF7(x float64) float64 {
-	v := dual.Cos(dual.Mul(dual.Mul(dual.Number{Real:2}, dual.Number{Real: math.Pi}), dual.Number{Real:x, Emag:1}))
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F12", Mode: autofd.Symbolic},
+		want: `func DerivF12(x float64) float64 {
+	return 6
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F8"},
-		want: `func DerivF8(x float64) float64 {
-	v := dual.Mul(dual.Exp(dual.Number{Real:x, Emag:1}), dual.Inv(dual.Sqrt(dual.Add(dual.Pow(dual.Sin(dual.Number{Real:x, Emag:1}), dual.Number{Real:3}), dual.Pow(dual.Cos(dual.Number{Real:x, Emag:1}), dual.Number{Real:3})))))
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F13", Mode: autofd.Symbolic},
+		want: `func DerivF13(x float64) float64 {
+	if x < 0 {
+		return -1
+	}
+	return 1
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F9"},
-		want: `func DerivF9(x float64) float64 {
-	v := dual.Mul(dual.Number{Real:pi}, dual.Number{Real:x, Emag:1})
-	return v.Emag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F14", Mode: autofd.Symbolic},
+		want: `func DerivF14(x float64) float64 {
+	switch {
+	case x < 0:
+		return -1
+	default:
+		return 1
+	}
 }
 `,
 	},
-	// second derivatives
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1"},
-		d2x:  true,
-		want: `func DerivF1(x float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Number{Real:x, E1mag:1, E2mag:1})
-	return v.E1mag, v.E1E2mag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "y", Mode: autofd.Symbolic},
+		want: `func DerivF21_y(x float64, n int, y float64) float64 {
+	return math.Pow(x, float64(n))
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1", Deriv: "DxF1"},
-		d2x:  true,
-		want: `func DxF1(x float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Number{Real:x, E1mag:1, E2mag:1})
-	return v.E1mag, v.E1E2mag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F23", Mode: autofd.Symbolic},
+		want: `func DerivF23(x float64) float64 {
+	if x*x+1 > 2 {
+		return (2*x - 2*x*math.Log(x*x+1)) / ((x*x + 1) * (x*x + 1))
+	}
+	return x / math.Sqrt(x*x+1)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.F", Deriv: "DxF"},
-		d2x:  true,
-		want: `func DxF(x float64) (d1, d2 float64) {
-	v := hyperdual.Add(hyperdual.Add(hyperdual.Mul(hyperdual.Number{Real:2}, hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Mul(hyperdual.Mul(hyperdual.Number{Real:3}, hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Number{Real:x, E1mag:1, E2mag:1})), hyperdual.Mul(hyperdual.Number{Real:4}, hyperdual.Pow(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Number{Real:3})))
-	return v.E1mag, v.E1E2mag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1[float64]", Mode: autofd.Symbolic},
+		want: `func DerivP1_float64(x float64) float64 {
+	return 6*x + math.Cos(x)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T2.F", Deriv: "DxF"},
-		d2x:  true,
-		want: `func DxF(x float64) (d1, d2 float64) {
-	v := hyperdual.Add(hyperdual.Add(hyperdual.Mul(hyperdual.Number{Real:2}, hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Mul(hyperdual.Mul(hyperdual.Number{Real:3}, hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Number{Real:x, E1mag:1, E2mag:1})), hyperdual.Mul(hyperdual.Number{Real:4}, hyperdual.Pow(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Number{Real:3})))
-	return v.E1mag, v.E1E2mag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.F", Mode: autofd.Symbolic},
+		want: `func DerivT1_F(x float64) float64 {
+	return 2 + 6*x + 12*math.Pow(x, 2)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F2"},
-		d2x:  true,
-		want: `func DerivF2(y float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Number{Real:y, E1mag:1, E2mag:1}, hyperdual.Number{Real:y, E1mag:1, E2mag:1})
-	return v.E1mag, v.E1E2mag
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F25", Mode: autofd.Symbolic},
+		want: `func DerivF25(x float64) float64 {
+	return 2*x/(1+x*x) + math.Exp(x) - 2*x/((1+x*x)*math.Ln2) + 1/((2+x)*math.Ln10) + 1/(3*math.Cbrt(x*x))
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F3"},
-		d2x:  true,
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F29", Mode: autofd.Symbolic},
+		want: `func DerivF29(x float64) float64 {
+	return math.Floor(x) + math.Cos(x)*math.Trunc(2*x) + 2*x
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F30", Mode: autofd.Symbolic},
+		want: `func DerivF30(x float64) float64 {
+	return sigmoid(2*x+1) + 2*sigmoidDeriv(2*x+1)*x + 2*sigmoid(x*x)*x
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F31", Mode: autofd.Symbolic},
+		want: `func DerivF31(x float64) float64 {
+	return (sigmoidDeriv2(x)*x - sigmoidDeriv(x)) / (x * x)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F32", Mode: autofd.Symbolic},
+		want: `func DerivF32(x float64) float64 {
+	return 1e+23*physconst.Boltzmann + math.Cos(x)*physconst.Scale - 2*x*physconst.Temperatures[1]/((x*x+1)*(x*x+1))
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F33", Mode: autofd.Symbolic},
+		want: `func DerivF33(x float64) float64 {
+	return m.Cos(pc.Scale*x)*pc.Scale + x/m.Hypot(x, pc.Boltzmann) + pc.Temperatures[0]/100*m.Pow(x, pc.Temperatures[0]/100-1)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F34", Mode: autofd.Symbolic},
+		want: `func DerivF34(x float64) float64 {
+	return 1.5707963267948966*math.Cos(x) + 2 + 0.6666666666666666*math.Exp(x/4) + 97 + quarter
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F35", Wrt: "x", Mode: autofd.Symbolic},
+		want: `func DerivF35_x(x float64, n int) float64 {
+	return 2*float64(n)*x - float64(n<<2)/(x*x) - math.Sin(float64(2*n)*x)*float64(2*n)*float64(len(coeffs)-1)
+}
+`,
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F39", Mode: autofd.Symbolic},
+		want: `func DerivF39(x float64) float64 {
+	return 1 + 3*x
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F3", Mode: autofd.Symbolic},
+		order: 2,
 		want: `func DerivF3(x float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Mul(hyperdual.Number{Real:2}, hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Number{Real:x, E1mag:1, E2mag:1})
-	return v.E1mag, v.E1E2mag
+	return 4 * x, 4
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F4"},
-		d2x:  true,
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F4", Mode: autofd.Symbolic},
+		order: 2,
 		want: `func DerivF4(x float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Number{Real:2}, hyperdual.Inv((hyperdual.Mul(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Number{Real:x, E1mag:1, E2mag:1}))))
-	return v.E1mag, v.E1E2mag
+	return -4 * x / (x * x * x * x), 12 * x * x * x * x / (x * x * x * x * x * x * x * x)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F5"},
-		d2x:  true,
-		want: `func DerivF5(x float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Number{Real:2}, hyperdual.Inv((hyperdual.Mul(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Mul(hyperdual.Number{Real:-1}, hyperdual.Number{Real:x, E1mag:1, E2mag:1})))))
-	return v.E1mag, v.E1E2mag
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F7", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF7(x float64) (d1, d2 float64) {
+	return -6.283185307179586 * math.Sin(6.283185307179586*x), -39.47841760435743 * math.Cos(6.283185307179586*x)
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F6"},
-		d2x:  true,
-		want: `func DerivF6(x float64) (d1, d2 float64) {
-	v := hyperdual.Sub(hyperdual.Add(hyperdual.Number{Real:2}, hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Number{Real:x, E1mag:1, E2mag:1})
-	return v.E1mag, v.E1E2mag
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F10", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF10(x float64) (d1, d2 float64) {
+	return 2 * math.Cos(x) * math.Sin(x), 2 * (-math.Sin(x)*math.Sin(x) + math.Cos(x)*math.Cos(x))
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F7"},
-		d2x:  true,
-		want: `func DerivF7(x float64) (d1, d2 float64) {
-	v := hyperdual.Cos(hyperdual.Mul(hyperdual.Mul(hyperdual.Number{Real:2}, hyperdual.Number{Real: math.Pi}), hyperdual.Number{Real:x, E1mag:1, E2mag:1}))
-	return v.E1mag, v.E1E2mag
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F12", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF12(x float64) (d1, d2 float64) {
+	return 6, 0
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F8"},
-		d2x:  true,
-		want: `func DerivF8(x float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Exp(hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Inv(hyperdual.Sqrt(hyperdual.Add(hyperdual.Pow(hyperdual.Sin(hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Number{Real:3}), hyperdual.Pow(hyperdual.Cos(hyperdual.Number{Real:x, E1mag:1, E2mag:1}), hyperdual.Number{Real:3})))))
-	return v.E1mag, v.E1E2mag
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F13", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF13(x float64) (d1, d2 float64) {
+	if x < 0 {
+		return -1, 0
+	}
+	return 1, 0
 }
 `,
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F9"},
-		d2x:  true,
-		want: `func DerivF9(x float64) (d1, d2 float64) {
-	v := hyperdual.Mul(hyperdual.Number{Real:pi}, hyperdual.Number{Real:x, E1mag:1, E2mag:1})
-	return v.E1mag, v.E1E2mag
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F14", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF14(x float64) (d1, d2 float64) {
+	switch {
+	case x < 0:
+		return -1, 0
+	default:
+		return 1, 0
+	}
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "y", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF21_y(x float64, n int, y float64) (d1, d2 float64) {
+	return math.Pow(x, float64(n)), 0
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F23", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF23(x float64) (d1, d2 float64) {
+	if x*x+1 > 2 {
+		return (2*x - 2*x*math.Log(x*x+1)) / ((x*x + 1) * (x*x + 1)), ((2-2*(math.Log(x*x+1)+2*x/(x*x+1)*x))*(x*x+1)*(x*x+1) - 4*x*(x*x+1)*(2*x-2*x*math.Log(x*x+1))) / ((x*x + 1) * (x*x + 1) * (x*x + 1) * (x*x + 1))
+	}
+	return x / math.Sqrt(x*x+1), (math.Sqrt(x*x+1) - x/math.Sqrt(x*x+1)*x) / (math.Sqrt(x*x+1) * math.Sqrt(x*x+1))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1[float64]", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivP1_float64(x float64) (d1, d2 float64) {
+	return 6*x + math.Cos(x), 6 - math.Sin(x)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.F", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivT1_F(x float64) (d1, d2 float64) {
+	return 2 + 6*x + 12*math.Pow(x, 2), 6 + 24*x
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F25", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF25(x float64) (d1, d2 float64) {
+	return 2*x/(1+x*x) + math.Exp(x) - 2*x/((1+x*x)*math.Ln2) + 1/((2+x)*math.Ln10) + 1/(3*math.Cbrt(x*x)), (2*(1+x*x)-4*x*x)/((1+x*x)*(1+x*x)) + math.Exp(x) - (2*(1+x*x)*math.Ln2-4*x*math.Ln2*x)/((1+x*x)*math.Ln2*(1+x*x)*math.Ln2) - math.Ln10/((2+x)*math.Ln10*(2+x)*math.Ln10) - 0.2222222222222222*(x/math.Cbrt(x*x*x*x)/(math.Cbrt(x*x)*math.Cbrt(x*x)))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F29", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF29(x float64) (d1, d2 float64) {
+	return math.Floor(x) + math.Cos(x)*math.Trunc(2*x) + 2*x, -math.Sin(x)*math.Trunc(2*x) + 2
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F30", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF30(x float64) (d1, d2 float64) {
+	return sigmoid(2*x+1) + 2*sigmoidDeriv(2*x+1)*x + 2*sigmoid(x*x)*x, 2*sigmoidDeriv(2*x+1) + 2*(2*sigmoidDeriv2(2*x+1)*x+sigmoidDeriv(2*x+1)) + 2*(2*sigmoidDeriv(x*x)*x*x+sigmoid(x*x))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F32", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF32(x float64) (d1, d2 float64) {
+	return 1e+23*physconst.Boltzmann + math.Cos(x)*physconst.Scale - 2*x*physconst.Temperatures[1]/((x*x+1)*(x*x+1)), -math.Sin(x)*physconst.Scale - (2*physconst.Temperatures[1]*(x*x+1)*(x*x+1)-8*x*(x*x+1)*x*physconst.Temperatures[1])/((x*x+1)*(x*x+1)*(x*x+1)*(x*x+1))
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F33", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF33(x float64) (d1, d2 float64) {
+	return m.Cos(pc.Scale*x)*pc.Scale + x/m.Hypot(x, pc.Boltzmann) + pc.Temperatures[0]/100*m.Pow(x, pc.Temperatures[0]/100-1), -m.Sin(pc.Scale*x)*pc.Scale*pc.Scale + (m.Hypot(x, pc.Boltzmann)-x/m.Hypot(x, pc.Boltzmann)*x)/(m.Hypot(x, pc.Boltzmann)*m.Hypot(x, pc.Boltzmann)) + (pc.Temperatures[0]/100-1)*m.Pow(x, pc.Temperatures[0]/100-1-1)*(pc.Temperatures[0]/100)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F34", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF34(x float64) (d1, d2 float64) {
+	return 1.5707963267948966*math.Cos(x) + 2 + 0.6666666666666666*math.Exp(x/4) + 97 + quarter, -1.5707963267948966*math.Sin(x) + 0.16666666666666666*math.Exp(x/4)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F35", Wrt: "x", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF35_x(x float64, n int) (d1, d2 float64) {
+	return 2*float64(n)*x - float64(n<<2)/(x*x) - math.Sin(float64(2*n)*x)*float64(2*n)*float64(len(coeffs)-1), 2*float64(n) + 2*x*float64(n<<2)/(x*x*x*x) - math.Cos(float64(2*n)*x)*float64(2*n)*float64(2*n)*float64(len(coeffs)-1)
+}
+`,
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F39", Mode: autofd.Symbolic},
+		order: 2,
+		want: `func DerivF39(x float64) (d1, d2 float64) {
+	return 1 + 3*x, 3
 }
 `,
 	},
@@ -262,6 +3809,14 @@ var derivativeTests = []struct {
 		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF1"},
 		err:  fmt.Errorf("could not create derivative generator: invalid function signature for ErrF1"),
 	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "n"},
+		err:  fmt.Errorf("could not create derivative generator: invalid function signature for F21"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F21", Wrt: "z"},
+		err:  fmt.Errorf("could not create derivative generator: could not find parameter z of F21"),
+	},
 	{
 		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF2"},
 		err:  fmt.Errorf("could not create derivative generator: invalid function signature for ErrF2"),
@@ -279,16 +3834,78 @@ var derivativeTests = []struct {
 		err:  fmt.Errorf("could not generate derivative: naked returns not supported"),
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF6"},
-		err:  fmt.Errorf("could not generate derivative: can not handle functions with multiple return statements"),
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF9"},
+		err:  fmt.Errorf("could not generate derivative: can not assign to x"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF10"},
+		err:  fmt.Errorf("could not generate derivative: invalid conversion float64(float32(x))"),
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "J1"},
+		order: 2,
+		err:   fmt.Errorf("could not create derivative generator: second derivatives of J1 not supported"),
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "J1"},
+		order: 3,
+		err:   fmt.Errorf("could not create derivative generator: derivatives of order 3 of J1 not supported"),
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G1"},
+		order: 3,
+		err:   fmt.Errorf("could not create derivative generator: derivatives of order 3 of G1 not supported"),
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1"},
+		order: -1,
+		err:   fmt.Errorf("could not create derivative generator: invalid derivative order -1"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1", Mode: autofd.Reverse},
+		err:  fmt.Errorf("could not create derivative generator: reverse mode derivatives of F1 not supported"),
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "C1"},
+		order: 2,
+		err:   fmt.Errorf("could not create derivative generator: derivatives of order 2 of C1 not supported"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrC1"},
+		err:  fmt.Errorf("could not generate derivative: invalid call to builtin complex(cmplx.Abs(z), 0)"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1"},
+		err:  fmt.Errorf("could not create derivative generator: got 0 type arguments for P1, want 1"),
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF7"},
-		err:  fmt.Errorf("could not generate derivative: can not handle functions with multiple return statements"),
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F1[float64]"},
+		err:  fmt.Errorf("could not create derivative generator: F1 is not a generic function"),
 	},
 	{
-		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF8"},
-		err:  fmt.Errorf("could not generate derivative: can not handle functions with multiple return statements"),
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "P1[Txxx]"},
+		err:  fmt.Errorf("could not create derivative generator: could not find type Txxx"),
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G1", Mode: autofd.Reverse},
+		order: 2,
+		err:   fmt.Errorf("could not create derivative generator: reverse mode derivatives of order 2 not supported"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrJ1"},
+		err:  fmt.Errorf("could not generate derivative: can not assign to x[0]"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF11"},
+		err:  fmt.Errorf("could not generate derivative: could not generate dual_split: can not handle multi-valued assignments"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF12"},
+		err:  fmt.Errorf("could not generate derivative: can not assign float32(x) to y of type float32"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrF13"},
+		err:  fmt.Errorf("could not generate derivative: can not assign &x to p of type *float64"),
 	},
 	{
 		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfuncXXX", Name: "F1"},
@@ -310,6 +3927,10 @@ var derivativeTests = []struct {
 		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1"},
 		err:  fmt.Errorf(`could not create derivative generator: object T1 in package "gonum.org/v1/tools/autofd/internal/testfunc" is not a func (*types.TypeName)`),
 	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrV1"},
+		err:  fmt.Errorf(`could not create derivative generator: object ErrV1 in package "gonum.org/v1/tools/autofd/internal/testfunc" is not a func (*types.Var)`),
+	},
 	{
 		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "T1.Fxxx"},
 		err:  fmt.Errorf(`could not create derivative generator: could not find T1.Fxxx in package "gonum.org/v1/tools/autofd/internal/testfunc"`),
@@ -318,4 +3939,45 @@ var derivativeTests = []struct {
 		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "ErrT1.F"},
 		err:  fmt.Errorf(`could not create derivative generator: could not find ErrT1.F in package "gonum.org/v1/tools/autofd/internal/testfunc"`),
 	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F15", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not generate derivative: branches must end with a return statement in symbolic mode"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F17", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not generate derivative: invalid statement type in symbolic mode: *ast.ForStmt"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F22", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not generate derivative: could not inline poly: invalid statement type in symbolic mode: *ast.ForStmt"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G1", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not create derivative generator: symbolic derivatives of G1 not supported"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "S1", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not create derivative generator: symbolic derivatives of S1 not supported"),
+	},
+	{
+		name:  autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F26"},
+		order: 3,
+		err:   fmt.Errorf("could not generate derivative: derivatives of order 3 of math.Gamma not supported"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F26", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not generate derivative: can not derive math.Gamma(x + 3) symbolically"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F27", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not generate derivative: can not handle multi-valued assignments"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "F28", Mode: autofd.Symbolic},
+		err:  fmt.Errorf("could not generate derivative: can not derive math.Max(0, x * x - 0.5) symbolically"),
+	},
+	{
+		name: autofd.Func{Path: "gonum.org/v1/tools/autofd/internal/testfunc", Name: "G6", Mode: autofd.Reverse, Subgradient: autofd.RightSubgradient},
+		err:  fmt.Errorf("could not generate derivative: reverse mode right subgradients of math.Max not supported"),
+	},
 }
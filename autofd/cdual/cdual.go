@@ -0,0 +1,196 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cdual provides commutative dual complex numbers, used by autofd
+// to generate derivatives of holomorphic functions.
+//
+// Unlike the anti-commutative numbers of gonum.org/v1/gonum/num/dualcmplx,
+// whose products conjugate their operands, the dual part of the result of
+// a holomorphic function f applied to z+ϵ is the complex derivative f'(z).
+package cdual // import "gonum.org/v1/tools/autofd/cdual"
+
+import "math/cmplx"
+
+// Number is a float64 precision commutative dual complex number.
+type Number struct {
+	Real, Emag complex128
+}
+
+// Add returns the sum of x and y.
+func Add(x, y Number) Number {
+	return Number{
+		Real: x.Real + y.Real,
+		Emag: x.Emag + y.Emag,
+	}
+}
+
+// Sub returns the difference of x and y, x-y.
+func Sub(x, y Number) Number {
+	return Number{
+		Real: x.Real - y.Real,
+		Emag: x.Emag - y.Emag,
+	}
+}
+
+// Mul returns the dual product of x and y.
+func Mul(x, y Number) Number {
+	return Number{
+		Real: x.Real * y.Real,
+		Emag: x.Real*y.Emag + x.Emag*y.Real,
+	}
+}
+
+// Scale returns x scaled by f.
+func Scale(f complex128, x Number) Number {
+	return Number{
+		Real: f * x.Real,
+		Emag: f * x.Emag,
+	}
+}
+
+// Inv returns the dual inverse of x.
+func Inv(x Number) Number {
+	v := 1 / x.Real
+	return Number{
+		Real: v,
+		Emag: -x.Emag * v * v,
+	}
+}
+
+// Exp returns e**x, the base-e exponential of x.
+func Exp(x Number) Number {
+	v := cmplx.Exp(x.Real)
+	return Number{
+		Real: v,
+		Emag: v * x.Emag,
+	}
+}
+
+// Log returns the natural logarithm of x.
+func Log(x Number) Number {
+	return Number{
+		Real: cmplx.Log(x.Real),
+		Emag: x.Emag / x.Real,
+	}
+}
+
+// Pow returns x**p, the base-x exponential of p.
+func Pow(x, p Number) Number {
+	v := cmplx.Pow(x.Real, p.Real)
+	d := p.Real * cmplx.Pow(x.Real, p.Real-1) * x.Emag
+	if p.Emag != 0 {
+		d += v * cmplx.Log(x.Real) * p.Emag
+	}
+	return Number{
+		Real: v,
+		Emag: d,
+	}
+}
+
+// Sqrt returns the square root of x.
+func Sqrt(x Number) Number {
+	v := cmplx.Sqrt(x.Real)
+	return Number{
+		Real: v,
+		Emag: x.Emag / (2 * v),
+	}
+}
+
+// Sin returns the sine of x.
+func Sin(x Number) Number {
+	return Number{
+		Real: cmplx.Sin(x.Real),
+		Emag: cmplx.Cos(x.Real) * x.Emag,
+	}
+}
+
+// Cos returns the cosine of x.
+func Cos(x Number) Number {
+	return Number{
+		Real: cmplx.Cos(x.Real),
+		Emag: -cmplx.Sin(x.Real) * x.Emag,
+	}
+}
+
+// Tan returns the tangent of x.
+func Tan(x Number) Number {
+	v := cmplx.Tan(x.Real)
+	return Number{
+		Real: v,
+		Emag: (1 + v*v) * x.Emag,
+	}
+}
+
+// Sinh returns the hyperbolic sine of x.
+func Sinh(x Number) Number {
+	return Number{
+		Real: cmplx.Sinh(x.Real),
+		Emag: cmplx.Cosh(x.Real) * x.Emag,
+	}
+}
+
+// Cosh returns the hyperbolic cosine of x.
+func Cosh(x Number) Number {
+	return Number{
+		Real: cmplx.Cosh(x.Real),
+		Emag: cmplx.Sinh(x.Real) * x.Emag,
+	}
+}
+
+// Tanh returns the hyperbolic tangent of x.
+func Tanh(x Number) Number {
+	v := cmplx.Tanh(x.Real)
+	return Number{
+		Real: v,
+		Emag: (1 - v*v) * x.Emag,
+	}
+}
+
+// Asin returns the inverse sine of x.
+func Asin(x Number) Number {
+	return Number{
+		Real: cmplx.Asin(x.Real),
+		Emag: x.Emag / cmplx.Sqrt(1-x.Real*x.Real),
+	}
+}
+
+// Acos returns the inverse cosine of x.
+func Acos(x Number) Number {
+	return Number{
+		Real: cmplx.Acos(x.Real),
+		Emag: -x.Emag / cmplx.Sqrt(1-x.Real*x.Real),
+	}
+}
+
+// Atan returns the inverse tangent of x.
+func Atan(x Number) Number {
+	return Number{
+		Real: cmplx.Atan(x.Real),
+		Emag: x.Emag / (1 + x.Real*x.Real),
+	}
+}
+
+// Asinh returns the inverse hyperbolic sine of x.
+func Asinh(x Number) Number {
+	return Number{
+		Real: cmplx.Asinh(x.Real),
+		Emag: x.Emag / cmplx.Sqrt(1+x.Real*x.Real),
+	}
+}
+
+// Acosh returns the inverse hyperbolic cosine of x.
+func Acosh(x Number) Number {
+	return Number{
+		Real: cmplx.Acosh(x.Real),
+		Emag: x.Emag / (cmplx.Sqrt(x.Real-1) * cmplx.Sqrt(x.Real+1)),
+	}
+}
+
+// Atanh returns the inverse hyperbolic tangent of x.
+func Atanh(x Number) Number {
+	return Number{
+		Real: cmplx.Atanh(x.Real),
+		Emag: x.Emag / (1 - x.Real*x.Real),
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdual
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestDerivative(t *testing.T) {
+	const (
+		h   = 1e-6
+		tol = 1e-7
+	)
+	for _, test := range []struct {
+		name string
+		fn   func(z Number) Number
+		fz   func(z complex128) complex128
+	}{
+		{
+			name: "z*z-1/z",
+			fn:   func(z Number) Number { return Sub(Mul(z, z), Inv(z)) },
+			fz:   func(z complex128) complex128 { return z*z - 1/z },
+		},
+		{
+			name: "(1+2i)z+z",
+			fn:   func(z Number) Number { return Add(Scale(1+2i, z), z) },
+			fz:   func(z complex128) complex128 { return (1+2i)*z + z },
+		},
+		{name: "exp", fn: Exp, fz: cmplx.Exp},
+		{name: "log", fn: Log, fz: cmplx.Log},
+		{name: "sqrt", fn: Sqrt, fz: cmplx.Sqrt},
+		{
+			name: "pow",
+			fn:   func(z Number) Number { return Pow(z, z) },
+			fz:   func(z complex128) complex128 { return cmplx.Pow(z, z) },
+		},
+		{
+			name: "pow3",
+			fn:   func(z Number) Number { return Pow(z, Number{Real: 3}) },
+			fz:   func(z complex128) complex128 { return z * z * z },
+		},
+		{name: "sin", fn: Sin, fz: cmplx.Sin},
+		{name: "cos", fn: Cos, fz: cmplx.Cos},
+		{name: "tan", fn: Tan, fz: cmplx.Tan},
+		{name: "sinh", fn: Sinh, fz: cmplx.Sinh},
+		{name: "cosh", fn: Cosh, fz: cmplx.Cosh},
+		{name: "tanh", fn: Tanh, fz: cmplx.Tanh},
+		{name: "asin", fn: Asin, fz: cmplx.Asin},
+		{name: "acos", fn: Acos, fz: cmplx.Acos},
+		{name: "atan", fn: Atan, fz: cmplx.Atan},
+		{name: "asinh", fn: Asinh, fz: cmplx.Asinh},
+		{name: "acosh", fn: Acosh, fz: cmplx.Acosh},
+		{name: "atanh", fn: Atanh, fz: cmplx.Atanh},
+	} {
+		for _, z := range []complex128{0.3 + 0.4i, -1.2 + 0.5i, 2 - 1i} {
+			got := test.fn(Number{Real: z, Emag: 1})
+			if want := test.fz(z); cmplx.Abs(got.Real-want) > tol*cmplx.Abs(want) {
+				t.Errorf("unexpected value of %s(%v): got=%v want=%v", test.name, z, got.Real, want)
+			}
+			// The derivative of a holomorphic function is the same
+			// along the real and imaginary axes.
+			for _, dz := range []complex128{h, h * 1i} {
+				want := (test.fz(z+dz) - test.fz(z-dz)) / (2 * dz)
+				if cmplx.Abs(got.Emag-want) > tol*cmplx.Abs(want) {
+					t.Errorf("unexpected derivative of %s(%v): got=%v want=%v", test.name, z, got.Emag, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autofd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testPkgs maps the names of the packages referred to by the generated tests
+// to their import path.
+var testPkgs = map[string]string{
+	"cmplx":   "math/cmplx",
+	"fd":      "gonum.org/v1/gonum/diff/fd",
+	"math":    "math",
+	"testing": "testing",
+}
+
+// FileTest generates a complete, gofmt'd, Go test file checking the derivatives
+// generated by File with the same arguments against finite differences
+// computed with the gonum.org/v1/gonum/diff/fd package.
+//
+// Each derivative is evaluated at a fixed set of sample points. The dimension
+// of the points of multivariate functions and the number of rows of Jacobians
+// are the largest constant indices of their x and dst parameters plus one.
+// First derivatives are checked against central differences of the derived
+// function, and derivatives of order k > 1 against central differences of the
+// derivative of order k-1. Hessians, checked against second-order central
+// differences, are compared with a relative tolerance of 1e-3 instead of 1e-6.
+// Samples at which finite differences are NaN are skipped.
+//
+// Parameters other than the one derivatives are taken with respect to are
+// passed fixed values: 2 for integers, 0.7 for floating-point and complex
+// numbers, and the zero value for other types. Methods are called on the
+// zero value of their receiver type.
+func FileTest(w io.Writer, name string, fcts []Func, order int) error {
+	derivs := make([]derivation, len(fcts))
+	for i, f := range fcts {
+		derivs[i] = derivation{f: f, order: order}
+	}
+	return file(w, name, derivs, make(loader), true)
+}
+
+// check generates a test function checking the derivative against
+// finite differences.
+// The derived function is referred to as fn in the generated code,
+// and as name in the messages of the test.
+func (g *generator) check() error {
+	fn := g.fct.Name()
+	if tparams := g.fct.Type().(*types.Signature).TypeParams(); tparams.Len() > 0 {
+		targs := make([]string, tparams.Len())
+		for i := range targs {
+			targs[i] = g.typeString(g.targs[tparams.At(i)])
+		}
+		fn += "[" + strings.Join(targs, ", ") + "]"
+	}
+	name := fn
+
+	r, size := utf8.DecodeRuneInString(g.der)
+	g.printf("func Test%c%s(t *testing.T) {\n", unicode.ToUpper(r), g.der[size:])
+	if recv := g.sig.Recv(); recv != nil {
+		typ := recv.Type()
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		g.printf("\tvar recv %s\n", g.typeString(typ))
+		fn = "recv." + fn
+		name = g.typeString(typ) + "." + name
+	}
+
+	switch g.form {
+	case f1xForm:
+		g.checkScalar(fn, name)
+	case fnxForm:
+		g.checkGradient(fn, name)
+	case fmnxForm:
+		g.checkJacobian(fn, name)
+	}
+	g.printf("}\n")
+	return g.err
+}
+
+// checkScalar generates the checks of the derivatives of a function
+// with a single differentiated parameter.
+func (g *generator) checkScalar(fn, name string) {
+	var (
+		x       = "x"
+		f64     = "%s"
+		step    = ""
+		tol     = "1e-6"
+		samples = "[]float64{-1.3, -0.4, 0.3, 0.9, 2.1}"
+	)
+	switch {
+	case g.isComplex():
+		samples = "[]complex128{0.3 + 0.4i, -1.2 + 0.5i, 2 - 1i}"
+	case types.Identical(g.scalar, types.Typ[types.Float32]):
+		// Finite differences of float32 functions need larger steps.
+		x = "float32(x)"
+		f64 = "float64(%s)"
+		step = ", Step: 1e-2"
+		tol = "1e-2"
+	}
+	args := g.checkArgs(x)
+
+	if g.isComplex() {
+		g.printf("\tf := func(x complex128) complex128 {\n\t\treturn %s(%s)\n\t}\n", fn, args)
+	} else {
+		g.printf("\tf := func(x float64) float64 {\n\t\treturn %s\n\t}\n", fmt.Sprintf(f64, fn+"("+args+")"))
+	}
+	g.printf("\tfor _, x := range %s {\n", samples)
+	ds := make([]string, g.order)
+	for i := range ds {
+		ds[i] = fmt.Sprintf("d%d", i+1)
+	}
+	g.printf("\t\t%s := %s(%s)\n", strings.Join(ds, ", "), g.der, args)
+
+	if g.isComplex() {
+		g.printf(`		want := complex(
+			fd.Derivative(func(h float64) float64 { return real(f(x + complex(h, 0))) }, 0, &fd.Settings{Formula: fd.Central}),
+			fd.Derivative(func(h float64) float64 { return imag(f(x + complex(h, 0))) }, 0, &fd.Settings{Formula: fd.Central}),
+		)
+		if !cmplx.IsNaN(want) && cmplx.Abs(d1-want) > %[1]s*math.Max(1, cmplx.Abs(want)) {
+			t.Errorf("unexpected derivative of %[2]s at %%v: got=%%v want=%%v", x, d1, want)
+		}
+	}
+`, tol, name)
+		return
+	}
+
+	for k := 1; k <= g.order; k++ {
+		switch k {
+		case 1:
+			g.printf("\t\twant := fd.Derivative(f, x, &fd.Settings{Formula: fd.Central%s})\n", step)
+		default:
+			prev := make([]string, g.order)
+			for i := range prev {
+				prev[i] = "_"
+			}
+			prev[k-2] = "d"
+			g.printf("\t\twant = fd.Derivative(func(x float64) float64 {\n")
+			g.printf("\t\t\t%s := %s(%s)\n", strings.Join(prev, ", "), g.der, args)
+			g.printf("\t\t\treturn %s\n", fmt.Sprintf(f64, "d"))
+			g.printf("\t\t}, x, &fd.Settings{Formula: fd.Central%s})\n", step)
+		}
+		g.printf("\t\tif !math.IsNaN(want) && math.Abs(%s-want) > %s*math.Max(1, math.Abs(want)) {\n", fmt.Sprintf(f64, ds[k-1]), tol)
+		g.printf("\t\t\tt.Errorf(\"unexpected derivative of order %d of %s at %%v: got=%%v want=%%v\", x, %s, want)\n", k, name, ds[k-1])
+		g.printf("\t\t}\n")
+	}
+	g.printf("\t}\n")
+}
+
+// checkArgs returns the arguments passed to the derived function and to
+// its derivative, with x the differentiated argument.
+func (g *generator) checkArgs(x string) string {
+	params := g.fct.Type().(*types.Signature).Params()
+	args := make([]string, params.Len())
+	for i := range args {
+		if params.At(i) == g.xvar {
+			args[i] = x
+			continue
+		}
+		typ := g.sig.Params().At(i).Type()
+		basic, _ := typ.Underlying().(*types.Basic)
+		switch {
+		case basic == nil:
+			args[i] = "*new(" + g.typeString(typ) + ")"
+		case basic.Info()&types.IsInteger != 0:
+			args[i] = "2"
+		case basic.Info()&(types.IsFloat|types.IsComplex) != 0:
+			args[i] = "0.7"
+		case basic.Info()&types.IsBoolean != 0:
+			args[i] = "false"
+		case basic.Info()&types.IsString != 0:
+			args[i] = `""`
+		default:
+			args[i] = "*new(" + g.typeString(typ) + ")"
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// checkSamples are the coordinates of the sample points of multivariate
+// functions, repeated for points of higher dimension.
+var checkSamples = [][]string{
+	{"0.3", "-0.4", "0.9", "2.1", "-0.7", "1.2", "0.5", "-1.6"},
+	{"-1.3", "0.9", "0.3", "-0.4", "1.1", "-0.2", "1.7", "0.6"},
+}
+
+// checkPoints returns the sample points of dimension n of multivariate
+// functions.
+func checkPoints(n int) string {
+	points := make([]string, len(checkSamples))
+	for i, sample := range checkSamples {
+		x := make([]string, n)
+		for j := range x {
+			x[j] = sample[j%len(sample)]
+		}
+		points[i] = "{" + strings.Join(x, ", ") + "}"
+	}
+	return "[][]float64{" + strings.Join(points, ", ") + "}"
+}
+
+// checkDims returns the dimension of the sample points of a multivariate
+// function and its number of outputs, as the largest constant indices of its
+// x and dst parameters plus one.
+// Functions indexing x only with variables, as in loops over x, are evaluated
+// at points of dimension 4, and functions indexing dst only with variables
+// have as many outputs as inputs.
+func (g *generator) checkDims() (n, m int) {
+	fct := g.decl(g.fct)
+	var params []types.Object
+	for _, field := range fct.Type.Params.List {
+		for _, name := range field.Names {
+			params = append(params, g.pkg.TypesInfo.Defs[name])
+		}
+	}
+	x, dst := params[0], types.Object(nil)
+	if g.form == fmnxForm {
+		x, dst = params[1], params[0]
+	}
+
+	ast.Inspect(fct.Body, func(node ast.Node) bool {
+		expr, ok := node.(*ast.IndexExpr)
+		if !ok {
+			return true
+		}
+		id, ok := unparen(expr.X).(*ast.Ident)
+		if !ok {
+			return true
+		}
+		v := g.pkg.TypesInfo.Types[expr.Index].Value
+		if v == nil {
+			return true
+		}
+		i, ok := constant.Int64Val(constant.ToInt(v))
+		if !ok {
+			return true
+		}
+		switch g.pkg.TypesInfo.Uses[id] {
+		case x:
+			if int(i) >= n {
+				n = int(i) + 1
+			}
+		case dst:
+			if int(i) >= m {
+				m = int(i) + 1
+			}
+		}
+		return true
+	})
+	if n == 0 {
+		n = 4
+	}
+	if m == 0 {
+		m = n
+	}
+	return n, m
+}
+
+// checkGradient generates the checks of the gradient or the Hessian of
+// a multivariate function.
+func (g *generator) checkGradient(fn, name string) {
+	n, _ := g.checkDims()
+	g.printf("\tf := %s\n", fn)
+	g.printf("\tfor _, x := range %s {\n", checkPoints(n))
+	switch g.order {
+	case 1:
+		g.printf(`		got := make([]float64, len(x))
+		%[1]s(got, x)
+		want := fd.Gradient(nil, f, x, &fd.Settings{Formula: fd.Central})
+		for i := range want {
+			if !math.IsNaN(want[i]) && math.Abs(got[i]-want[i]) > 1e-6*math.Max(1, math.Abs(want[i])) {
+				t.Errorf("unexpected gradient of %[2]s at %%v: got=%%v want=%%v", x, got, want)
+				break
+			}
+		}
+	}
+`, g.der, name)
+	default:
+		g.printf(`		got := mat.NewSymDense(len(x), nil)
+		%[1]s(got, x)
+		want := mat.NewSymDense(len(x), nil)
+		fd.Hessian(want, f, x, &fd.Settings{Formula: fd.Central})
+		ok := true
+		for i := range x {
+			for j := i; j < len(x); j++ {
+				w := want.At(i, j)
+				if !math.IsNaN(w) && math.Abs(got.At(i, j)-w) > 1e-3*math.Max(1, math.Abs(w)) {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			t.Errorf("unexpected Hessian of %[2]s at %%v:\ngot=%%v\nwant=%%v", x, mat.Formatted(got), mat.Formatted(want))
+		}
+	}
+`, g.der, name)
+	}
+}
+
+// checkJacobian generates the checks of the Jacobian of a multivariate
+// vector-valued function.
+func (g *generator) checkJacobian(fn, name string) {
+	n, m := g.checkDims()
+	g.printf("\tf := %s\n", fn)
+	g.printf(`	const m = %[4]d
+	for _, x := range %[3]s {
+		got := mat.NewDense(m, len(x), nil)
+		%[1]s(got, x)
+		want := mat.NewDense(m, len(x), nil)
+		fd.Jacobian(want, f, x, &fd.JacobianSettings{Formula: fd.Central})
+		ok := true
+		for i := 0; i < m; i++ {
+			for j := range x {
+				w := want.At(i, j)
+				if !math.IsNaN(w) && math.Abs(got.At(i, j)-w) > 1e-6*math.Max(1, math.Abs(w)) {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			t.Errorf("unexpected Jacobian of %[2]s at %%v:\ngot=%%v\nwant=%%v", x, mat.Formatted(got), mat.Formatted(want))
+		}
+	}
+`, g.der, name, checkPoints(n), m)
+}
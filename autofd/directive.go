@@ -0,0 +1,275 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autofd
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// directive is the prefix of the comments marking the declarations to derive.
+const directive = "//autofd:derive"
+
+// ruleDirective is the prefix of the comments declaring the derivative
+// of a function.
+const ruleDirective = "//autofd:rule"
+
+// Package generates a complete Go source file, as File does, holding the
+// derivatives of the functions of the package matching the provided import
+// path or pattern, such as ".", whose declarations are marked with a directive:
+//
+//	//autofd:derive [name] [-d2] [-order n] [-wrt param] [-mode reverse|symbolic] [-subgradient left|right|zero]
+//
+// The directive is placed in the doc comment of a function or method
+// declaration, or of a package-level variable initialized with a function
+// literal. The optional name is the name of the derivative, and the options
+// select the order of the derivatives and the Wrt, Mode and Subgradient
+// fields of Func.
+// -d2 is a shorthand for -order 2, and can not be combined with another order.
+//
+// A declaration may hold several directives with distinct derivative names.
+// The file declares the package of the derived functions.
+func Package(w io.Writer, path string) error {
+	return packageFile(w, path, false)
+}
+
+// PackageTest generates a Go test file, as FileTest does, checking the
+// derivatives generated by Package for the package matching the provided
+// import path or pattern.
+func PackageTest(w io.Writer, path string) error {
+	return packageFile(w, path, true)
+}
+
+// packageFile generates the file of the derivatives requested by the
+// directives of the package matching the provided import path or pattern,
+// or the file of their tests if test is true.
+func packageFile(w io.Writer, path string, test bool) error {
+	pkgs, err := packages.Load(&packages.Config{Mode: loadMode}, path)
+	if err != nil {
+		return fmt.Errorf("could not load package %q: %w", path, err)
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) > 0 {
+		return fmt.Errorf("could not find package %q", path)
+	}
+	pkg := pkgs[0]
+
+	derivs, err := directives(pkg)
+	if err != nil {
+		return err
+	}
+	return file(w, "", derivs, loader{pkg.PkgPath: pkg}, test)
+}
+
+// directives returns the derivatives requested by the directives
+// of the provided package, in declaration order.
+func directives(pkg *packages.Package) ([]derivation, error) {
+	var derivs []derivation
+	add := func(doc *ast.CommentGroup, name string) error {
+		if doc == nil {
+			return nil
+		}
+		for _, c := range doc.List {
+			if !isDirective(c.Text, directive) {
+				continue
+			}
+			d, err := parseDirective(Func{Path: pkg.PkgPath, Name: name}, c.Text)
+			if err != nil {
+				pos := pkg.Fset.Position(c.Pos())
+				return fmt.Errorf(
+					"invalid directive for %s at %s:%d: %w",
+					name, filepath.Base(pos.Filename), pos.Line, err,
+				)
+			}
+			derivs = append(derivs, d)
+		}
+		return nil
+	}
+
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			var err error
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				name := decl.Name.Name
+				if decl.Recv != nil {
+					name = recvName(decl.Recv.List[0].Type) + "." + name
+				}
+				err = add(decl.Doc, name)
+			case *ast.GenDecl:
+				if decl.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					if len(spec.Names) != 1 {
+						continue
+					}
+					doc := spec.Doc
+					if doc == nil && !decl.Lparen.IsValid() {
+						doc = decl.Doc
+					}
+					err = add(doc, spec.Names[0].Name)
+					if err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return derivs, nil
+}
+
+// isDirective returns whether the provided comment is a directive
+// with the provided prefix.
+func isDirective(text, prefix string) bool {
+	if !strings.HasPrefix(text, prefix) {
+		return false
+	}
+	rest := text[len(prefix):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// parseDirective returns the derivative of the provided function requested
+// by the provided directive.
+func parseDirective(f Func, text string) (derivation, error) {
+	d := derivation{f: f, order: 1}
+	args := strings.Fields(text[len(directive):])
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		d.f.Deriv, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet(directive, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	d2 := fs.Bool("d2", false, "")
+	fs.IntVar(&d.order, "order", d.order, "")
+	fs.StringVar(&d.f.Wrt, "wrt", "", "")
+	mode := fs.String("mode", "forward", "")
+	subgrad := fs.String("subgradient", "average", "")
+	err := fs.Parse(args)
+	if err != nil {
+		return d, err
+	}
+	if fs.NArg() != 0 {
+		return d, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if *d2 {
+		if d.order != 1 && d.order != 2 {
+			return d, fmt.Errorf("-d2 conflicts with -order %d", d.order)
+		}
+		d.order = 2
+	}
+	switch *mode {
+	case "forward":
+		d.f.Mode = Forward
+	case "reverse":
+		d.f.Mode = Reverse
+	case "symbolic":
+		d.f.Mode = Symbolic
+	default:
+		return d, fmt.Errorf("invalid differentiation mode %q", *mode)
+	}
+	switch *subgrad {
+	case "average":
+		d.f.Subgradient = AverageSubgradient
+	case "left":
+		d.f.Subgradient = LeftSubgradient
+	case "right":
+		d.f.Subgradient = RightSubgradient
+	case "zero":
+		d.f.Subgradient = ZeroSubgradient
+	default:
+		return d, fmt.Errorf("invalid subgradient %q", *subgrad)
+	}
+	return d, nil
+}
+
+// derivRules returns the derivatives of the functions of the provided
+// package declared by rule directives, of the form:
+//
+//	//autofd:rule MyFunc deriv=MyFuncPrime
+//
+// Rule directives may be placed in any comment of the package.
+func derivRules(pkg *packages.Package) (map[*types.Func]*types.Func, error) {
+	rules := make(map[*types.Func]*types.Func)
+	for _, f := range pkg.Syntax {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if !isDirective(c.Text, ruleDirective) {
+					continue
+				}
+				fct, deriv, err := parseRule(pkg, c.Text)
+				if err == nil && rules[fct] != nil {
+					err = fmt.Errorf("duplicate rule for %s", fct.Name())
+				}
+				if err != nil {
+					pos := pkg.Fset.Position(c.Pos())
+					return nil, fmt.Errorf(
+						"invalid rule at %s:%d: %w",
+						filepath.Base(pos.Filename), pos.Line, err,
+					)
+				}
+				rules[fct] = deriv
+			}
+		}
+	}
+	return rules, nil
+}
+
+// parseRule returns the function, and its derivative, of the provided
+// rule directive.
+func parseRule(pkg *packages.Package, text string) (fct, deriv *types.Func, err error) {
+	args := strings.Fields(text[len(ruleDirective):])
+	if len(args) != 2 || !strings.HasPrefix(args[1], "deriv=") {
+		return nil, nil, fmt.Errorf("expected %s name deriv=name", ruleDirective)
+	}
+	fct, err = lookupFunc(pkg, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	deriv, err = lookupFunc(pkg, strings.TrimPrefix(args[1], "deriv="))
+	if err != nil {
+		return nil, nil, err
+	}
+	return fct, deriv, nil
+}
+
+// lookupFunc returns the named function of the provided package, which must
+// have a func(float64) float64 signature.
+func lookupFunc(pkg *packages.Package, name string) (*types.Func, error) {
+	fct, ok := pkg.Types.Scope().Lookup(name).(*types.Func)
+	if !ok {
+		return nil, fmt.Errorf("could not find function %s", name)
+	}
+	if !types.Identical(fct.Type(), f1x.Type()) {
+		return nil, fmt.Errorf("invalid function signature for %s", name)
+	}
+	return fct, nil
+}
+
+// recvName returns the name of the type of the provided method receiver.
+func recvName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return recvName(expr.X)
+	case *ast.ParenExpr:
+		return recvName(expr.X)
+	case *ast.IndexExpr:
+		return recvName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return types.ExprString(expr)
+}
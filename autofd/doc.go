@@ -3,4 +3,118 @@
 // license that can be found in the LICENSE file.
 
 // Package autofd provides functions to generate derivatives.
+//
+// Derivative generates the derivatives of a function of a float64 variable
+// up to a given order. First and second derivatives are computed with dual
+// and hyperdual numbers, higher order derivatives with the truncated Taylor
+// series of the gonum.org/v1/tools/autofd/taylor package.
+// File and Package generate complete Go source files holding derivatives.
+//
+// # Function signatures
+//
+// Functions with a func(x []float64) float64 signature are derived into
+// a func(grad, x []float64) function filling grad with the gradient at x.
+// For second derivatives, a func(hess *mat.SymDense, x []float64) function
+// filling hess with the Hessian at x is generated instead.
+// Higher orders are not supported for these functions.
+//
+// Functions with a func(dst, x []float64) signature are derived into
+// a func(jac *mat.Dense, x []float64) function filling jac with the Jacobian
+// at x. As for fd.Jacobian, the number of rows of jac must be the length of dst.
+// Only first derivatives are supported for these functions.
+//
+// Functions with a func(x float32) float32 signature are derived into functions
+// with float32 parameters and results. Dual numbers hold float64 values: the
+// parameters are converted on entry and the derivatives on return.
+//
+// Functions with a func(z complex128) complex128 signature are derived into
+// a function returning their complex derivative, computed with the dual complex
+// numbers of the gonum.org/v1/tools/autofd/cdual package. Calls to functions
+// of the math/cmplx package are mapped to their cdual equivalents.
+// Only first derivatives are supported for these functions.
+//
+// Functions of several float64, float32 or complex128 parameters are derived
+// with respect to the parameter named by Func.Wrt. The other parameters are
+// passed through unchanged.
+//
+// Generic functions are derived for the type arguments listed after their
+// name in Func.Name, as in "F[float64]". Type parameters are replaced by their
+// type arguments in the generated code, and the default derivative name is
+// suffixed with "_" and each type argument, as in DerivF_float64.
+//
+// Package-level variables initialized with a function literal, as in
+// var F = func(x float64) float64 { ... }, are derived as the function
+// literal.
+//
+// # Modes
+//
+// In the Reverse mode, gradients and Jacobians are computed with the
+// gonum.org/v1/tools/autofd/adjoint package: the function is evaluated
+// once, recording its operations, and each row of the result is obtained by
+// sweeping the recorded operations backward. Only first derivatives of
+// multivariate functions are supported in reverse mode.
+//
+// In the Symbolic mode, the derivatives of a function of a float64 variable
+// are generated as closed-form float64 expressions, e.g. 4 * x for
+// 2 * x * x. Local variables and calls to same-package functions are inlined,
+// and if and switch statements are reproduced, with their conditions inlined.
+// Other statements, such as loops, are not supported in symbolic mode, and
+// branches must end with a return statement.
+//
+// # Calls
+//
+// Calls to math.Atan2, Cbrt, Erf, Erfc, Expm1, Gamma, Hypot, Lgamma, Log10,
+// Log1p and Log2, which have no dual and hyperdual number equivalents, are
+// derived with functions applying their derivative rule, generated alongside
+// the derivative (e.g. dual_math_Erf). The derivatives of math.Gamma and
+// math.Lgamma refer to the gonum.org/v1/gonum/mathext package, and are only
+// supported for first and second derivatives in forward mode.
+//
+// Calls to math.Floor, Ceil and Trunc have zero derivatives, also at their
+// jumps, and calls to math.Mod are derived holding the truncated quotient of
+// their arguments constant. Calls to math.Max and math.Min, as in ReLU and
+// hinge functions, are derived as their selected argument. Where both
+// arguments are equal, the derivatives are selected by Func.Subgradient: left
+// and right subgradients select the argument by comparing the derivatives of
+// the arguments in increasing order, and are not supported in reverse mode,
+// where these derivatives are not known yet. Symbolic derivatives of math.Max
+// and math.Min are not supported.
+//
+// Functions of the same package called from the derived function, including
+// func variables and generic functions instantiated with the type arguments
+// inferred from their call, are followed: versions operating on dual numbers,
+// named after the dual number package (e.g. dual_f for f), are generated
+// alongside the derivative.
+//
+// Same-package functions of a single float64 variable may instead declare
+// their derivative, as opaque functions autofd can not follow, with a rule
+// directive in a comment of their package:
+//
+//	//autofd:rule MyFunc deriv=MyFuncPrime
+//
+// Calls to MyFunc are then derived by calling MyFuncPrime, a function of the
+// same signature. Second derivatives require a rule for MyFuncPrime too, and
+// higher order derivatives are not supported.
+//
+// # Values
+//
+// Selectors are resolved with the type information of the package, so that
+// the math package may be imported under another name. Constants and
+// variables of any imported package, as in physconst.Boltzmann * x, are not
+// differentiated, and their package is imported by the generated file.
+//
+// Constant expressions of int and float64 type, as pi / 2 or float64(1 << 3),
+// are folded to their exact value as computed by the type checker: 1 / 2 * x
+// is 0, as for the compiler. Conversions of non-differentiated integer values
+// to float64, as in float64(n) * x, are non-differentiated values.
+//
+// Local variables, slices and loop variables holding values that depend on
+// the derived parameter are computed on dual numbers. If, switch and for
+// statements are reproduced in the generated code, with their conditions
+// evaluated on the real part of the dual numbers.
+//
+// The dual number seeding the derived parameter is defined once, as a local
+// variable named after the parameter (e.g. xd for x). Subexpressions appearing
+// more than once in a return statement, an assignment or a declaration are
+// computed once, into local variables t1, t2, ... defined before the statement.
 package autofd // import "gonum.org/v1/tools/autofd"
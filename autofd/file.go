@@ -0,0 +1,261 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autofd
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// header is the comment marking files written by File as generated.
+const header = "// Code generated by autofd; DO NOT EDIT.\n"
+
+// backends maps the names of the packages referred to by the generated code
+// to their import path.
+var backends = map[string]string{
+	"adjoint":   "gonum.org/v1/tools/autofd/adjoint",
+	"cdual":     "gonum.org/v1/tools/autofd/cdual",
+	"dual":      "gonum.org/v1/gonum/num/dual",
+	"hyperdual": "gonum.org/v1/gonum/num/hyperdual",
+	"mat":       "gonum.org/v1/gonum/mat",
+	"math":      "math",
+	"mathext":   "gonum.org/v1/gonum/mathext",
+	"taylor":    "gonum.org/v1/tools/autofd/taylor",
+}
+
+// File generates a complete, gofmt'd, Go source file holding the derivatives
+// of the provided functions up to the provided order, as generated by
+// Derivative. The file is marked as generated, declares the named package,
+// or the package of the first function if name is empty, and imports the
+// packages referred to by the derivatives, each under a single name.
+//
+// Identifiers of the package of the derived functions are referred to
+// unqualified: the file is meant to be added to that package, and File
+// fails if the named package is another package and the derivatives refer
+// to such identifiers.
+// Versions of same-package helpers operating on dual numbers are only
+// generated once.
+func File(w io.Writer, name string, fcts []Func, order int) error {
+	derivs := make([]derivation, len(fcts))
+	for i, f := range fcts {
+		derivs[i] = derivation{f: f, order: order}
+	}
+	return file(w, name, derivs, make(loader), false)
+}
+
+// derivation describes a derivative to generate.
+type derivation struct {
+	f     Func
+	order int
+}
+
+// file generates a complete Go source file holding the provided derivatives,
+// or the tests of those derivatives if test is true.
+func file(w io.Writer, name string, derivs []derivation, pkgs loader, test bool) error {
+	if len(derivs) == 0 {
+		return fmt.Errorf("no function to derive")
+	}
+
+	var (
+		body  = new(bytes.Buffer)
+		names = make(map[string]bool)
+		paths = make(map[string]string)
+	)
+	for _, d := range derivs {
+		f := d.f
+		gen, err := newGenerator(body, f, d.order, pkgs)
+		if err != nil {
+			return fmt.Errorf("could not create derivative generator for %s: %w", f.Name, err)
+		}
+		if name == "" {
+			name = gen.pkg.Name
+		}
+		body.WriteString("\n")
+		start := body.Len()
+		if test {
+			err = gen.check()
+			if err == nil {
+				err = gen.unqualified(name, body.Bytes()[start:])
+			}
+			if err != nil {
+				return fmt.Errorf("could not generate test of derivative of %s: %w", f.Name, err)
+			}
+			continue
+		}
+
+		gen.helpers.names = names
+		for _, file := range gen.pkg.Syntax {
+			for _, spec := range file.Imports {
+				path, _ := strconv.Unquote(spec.Path.Value)
+				paths[importName(gen, spec.Name, path)] = path
+			}
+		}
+		err = gen.generate()
+		if err == nil {
+			err = gen.unqualified(name, body.Bytes()[start:])
+		}
+		if err != nil {
+			return fmt.Errorf("could not generate derivative of %s: %w", f.Name, err)
+		}
+	}
+	for pkg, path := range backends {
+		paths[pkg] = path
+	}
+	if test {
+		for pkg, path := range testPkgs {
+			paths[pkg] = path
+		}
+	}
+
+	src := new(bytes.Buffer)
+	fmt.Fprintf(src, "%s\npackage %s\n", header, name)
+	imports, decls, err := fileImports(name, body.Bytes(), paths)
+	if err != nil {
+		return err
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(src, "\nimport (\n")
+		std := true
+		for _, pkg := range imports {
+			path := paths[pkg]
+			if std && !isStd(path) {
+				std = false
+				src.WriteString("\n")
+			}
+			src.WriteString("\t")
+			if pkg != path[strings.LastIndex(path, "/")+1:] {
+				src.WriteString(pkg + " ")
+			}
+			fmt.Fprintf(src, "%q\n", path)
+		}
+		fmt.Fprintf(src, ")\n")
+	}
+	src.Write(decls)
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not format generated file: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// importName returns the name under which the package with the provided
+// import path is referred to in the package of the generator.
+func importName(g *generator, name *ast.Ident, path string) string {
+	if name != nil {
+		return name.Name
+	}
+	for _, pkg := range g.pkg.Types.Imports() {
+		if pkg.Path() == path {
+			return pkg.Name()
+		}
+	}
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// unqualified returns an error if the provided declarations, generated in
+// a file of the named package, refer to package-level identifiers of the
+// package of the derived function, which are not qualified.
+func (g *generator) unqualified(name string, decls []byte) error {
+	if name == g.pkg.Name {
+		return nil
+	}
+	src := append([]byte("package "+name+"\n"), decls...)
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return fmt.Errorf("could not parse generated code: %w", err)
+	}
+	for _, id := range f.Unresolved {
+		if g.pkg.Types.Scope().Lookup(id.Name) != nil {
+			return fmt.Errorf("can not refer to %s of package %s from package %s", id.Name, g.pkg.Name, name)
+		}
+	}
+	return nil
+}
+
+// fileImports returns the names of the packages referred to by the provided
+// declarations, sorted by import path, standard library packages first, and
+// the declarations where packages referred to under several names, as when
+// files of the derived functions import them under different names, are
+// referred to under a single name: the last element of their import path
+// if it is one of them.
+func fileImports(name string, decls []byte, paths map[string]string) ([]string, []byte, error) {
+	src := append([]byte("package "+name+"\n"), decls...)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse generated code: %w", err)
+	}
+
+	uses := make(map[string][]*ast.Ident)
+	names := make(map[string][]string)
+	for _, id := range f.Unresolved {
+		path, ok := paths[id.Name]
+		if !ok {
+			continue
+		}
+		if uses[id.Name] == nil {
+			names[path] = append(names[path], id.Name)
+		}
+		uses[id.Name] = append(uses[id.Name], id)
+	}
+
+	var (
+		imports []string
+		renames []*ast.Ident
+		local   = make(map[*ast.Ident]string)
+	)
+	for path, pkgs := range names {
+		sort.Strings(pkgs)
+		pkg := pkgs[0]
+		for _, p := range pkgs {
+			if p == path[strings.LastIndex(path, "/")+1:] {
+				pkg = p
+			}
+		}
+		imports = append(imports, pkg)
+		for _, p := range pkgs {
+			if p == pkg {
+				continue
+			}
+			for _, id := range uses[p] {
+				renames = append(renames, id)
+				local[id] = pkg
+			}
+		}
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		pi, pj := paths[imports[i]], paths[imports[j]]
+		if isStd(pi) != isStd(pj) {
+			return isStd(pi)
+		}
+		return pi < pj
+	})
+
+	// Identifiers are renamed from the end of the declarations,
+	// so that the offsets of the others are preserved.
+	sort.Slice(renames, func(i, j int) bool { return renames[i].Pos() > renames[j].Pos() })
+	off := len(src) - len(decls)
+	for _, id := range renames {
+		pos := fset.Position(id.Pos()).Offset - off
+		decls = append(decls[:pos:pos], append([]byte(local[id]), decls[pos+len(id.Name):]...)...)
+	}
+	return imports, decls, nil
+}
+
+// isStd returns whether the provided import path is the path of a package
+// of the standard library.
+func isStd(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
@@ -0,0 +1,199 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autofd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// hoist generates the definitions of variables holding the subexpressions
+// that appear more than once in the provided expressions, evaluated on dual
+// numbers. These subexpressions are then printed as their variable until
+// the current statement is generated.
+// Subexpressions are identified by their source: expressions of a single
+// statement denote the same values.
+func (g *generator) hoist(exprs ...ast.Expr) {
+	g.forget()
+	if g.header || g.err != nil {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, expr := range exprs {
+		g.count(expr, counts)
+	}
+	done := make(map[string]bool)
+	for _, expr := range exprs {
+		g.define(expr, counts, done)
+	}
+}
+
+// count counts the occurrences of the subexpressions of the provided
+// expression that may be hoisted. The subexpressions of repeated
+// subexpressions are only counted once.
+func (g *generator) count(expr ast.Expr, counts map[string]int) {
+	expr = unparen(expr)
+	if g.isHoistable(expr) {
+		key := types.ExprString(expr)
+		counts[key]++
+		if counts[key] > 1 {
+			return
+		}
+	}
+	for _, sub := range g.operands(expr) {
+		g.count(sub, counts)
+	}
+}
+
+// define generates the definitions of the variables holding the repeated
+// subexpressions of the provided expression, innermost first.
+func (g *generator) define(expr ast.Expr, counts map[string]int, done map[string]bool) {
+	expr = unparen(expr)
+	if !g.isHoistable(expr) {
+		for _, sub := range g.operands(expr) {
+			g.define(sub, counts, done)
+		}
+		return
+	}
+
+	key := types.ExprString(expr)
+	if done[key] {
+		return
+	}
+	done[key] = true
+	for _, sub := range g.operands(expr) {
+		g.define(sub, counts, done)
+	}
+	if counts[key] < 2 {
+		return
+	}
+
+	var v string
+	for {
+		g.ntemps++
+		v = fmt.Sprintf("t%d", g.ntemps)
+		if !g.idents[v] {
+			break
+		}
+	}
+	g.tab()
+	g.printf("%s := ", v)
+	g.expr(expr)
+	g.printf("\n")
+	if g.temps == nil {
+		g.temps = make(map[string]string)
+	}
+	g.temps[key] = v
+}
+
+// temp returns the variable holding the provided hoisted subexpression, if any.
+func (g *generator) temp(expr ast.Expr) (string, bool) {
+	expr = unparen(expr)
+	if g.temps == nil || !g.isHoistable(expr) {
+		return "", false
+	}
+	v, ok := g.temps[types.ExprString(expr)]
+	return v, ok
+}
+
+// forget discards the hoisted subexpressions.
+func (g *generator) forget() {
+	g.temps = nil
+}
+
+// isHoistable returns whether the provided expression computes a dual number
+// that may be hoisted out of its statement.
+// Variables, elements of slices and conversions are not worth hoisting.
+func (g *generator) isHoistable(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.BinaryExpr:
+		// ok
+	case *ast.UnaryExpr:
+		if expr.Op != token.SUB {
+			return false
+		}
+	case *ast.CallExpr:
+		if g.pkg.TypesInfo.Types[expr.Fun].IsType() || g.isBuiltin(expr) {
+			return false
+		}
+	default:
+		return false
+	}
+	return g.isFloat(expr) && g.depends(expr)
+}
+
+// operands returns the subexpressions of the provided expression that are
+// evaluated on dual numbers.
+func (g *generator) operands(expr ast.Expr) []ast.Expr {
+	if !g.isFloat(expr) || !g.depends(expr) {
+		return nil
+	}
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return []ast.Expr{expr.X}
+	case *ast.UnaryExpr:
+		return []ast.Expr{expr.X}
+	case *ast.BinaryExpr:
+		return []ast.Expr{expr.X, expr.Y}
+	case *ast.CallExpr:
+		switch {
+		case g.pkg.TypesInfo.Types[expr.Fun].IsType():
+			return expr.Args
+		case g.isBuiltin(expr), g.isStep(expr):
+			return nil
+		}
+		if fct := g.callee(expr); fct != nil {
+			targs, ok := g.helpers.targs[fct]
+			if !ok {
+				targs = g.infer(fct, expr)
+			}
+			params := fct.Type().(*types.Signature).Params()
+			var args []ast.Expr
+			for i, arg := range expr.Args {
+				if g.isFloatType(subst(targs, params.At(i).Type())) {
+					args = append(args, arg)
+				}
+			}
+			return args
+		}
+		if g.isPowReal(expr) {
+			return expr.Args[:1]
+		}
+		return expr.Args
+	}
+	return nil
+}
+
+// dualRhs returns the right-hand sides of the provided assignment that are
+// evaluated on dual numbers.
+func (g *generator) dualRhs(stmt *ast.AssignStmt) []ast.Expr {
+	if len(stmt.Lhs) != len(stmt.Rhs) {
+		return nil
+	}
+	if _, ok := assignOps[stmt.Tok]; !ok && stmt.Tok != token.DEFINE && stmt.Tok != token.ASSIGN {
+		return nil
+	}
+	var rhs []ast.Expr
+	for i, lhs := range stmt.Lhs {
+		if g.isLifted(lhs) {
+			rhs = append(rhs, stmt.Rhs[i])
+		}
+	}
+	return rhs
+}
+
+// unparen returns the provided expression without its enclosing parentheses.
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
+	}
+}
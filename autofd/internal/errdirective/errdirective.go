@@ -0,0 +1,16 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package errdirective holds invalid autofd directives.
+package errdirective // import "gonum.org/v1/tools/autofd/internal/errdirective"
+
+//autofd:derive
+func F1(x float64) float64 {
+	return x * x
+}
+
+//autofd:derive DxF2 -order two
+func F2(x float64) float64 {
+	return x * x
+}
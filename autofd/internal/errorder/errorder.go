@@ -0,0 +1,11 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package errorder holds autofd directives with conflicting orders.
+package errorder // import "gonum.org/v1/tools/autofd/internal/errorder"
+
+//autofd:derive -d2 -order 3
+func F1(x float64) float64 {
+	return x * x
+}
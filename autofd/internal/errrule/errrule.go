@@ -0,0 +1,20 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package errrule holds invalid autofd rule directives.
+package errrule // import "gonum.org/v1/tools/autofd/internal/errrule"
+
+//autofd:derive
+func F1(x float64) float64 {
+	return f(x) * x
+}
+
+//autofd:rule f deriv=df
+func f(x float64) float64 {
+	return x * x
+}
+
+func df(x, y float64) float64 {
+	return 2 * x
+}
@@ -0,0 +1,16 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package physconst holds constants and variables used by the functions
+// of the testfunc package.
+package physconst // import "gonum.org/v1/tools/autofd/internal/physconst"
+
+// Boltzmann is the Boltzmann constant, in J/K.
+const Boltzmann = 1.380649e-23
+
+// Scale is a package-level float64 variable.
+var Scale = 2.5
+
+// Temperatures is a package-level slice of float64 values.
+var Temperatures = []float64{273.15, 293.15}
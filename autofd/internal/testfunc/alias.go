@@ -0,0 +1,17 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testfunc
+
+import (
+	m "math"
+
+	pc "gonum.org/v1/tools/autofd/internal/physconst"
+)
+
+// The functions of this file refer to imported packages by another name.
+
+func F33(x float64) float64 {
+	return m.Sin(pc.Scale*x) + m.Hypot(x, pc.Boltzmann) + m.Pow(x, pc.Temperatures[0]/100)
+}
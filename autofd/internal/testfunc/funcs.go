@@ -10,10 +10,19 @@ package testfunc // import "gonum.org/v1/tools/autofd/internal/testfunc"
 // in ../../autofd_test.go:derivativeTests when a new function is added
 // to this package.
 
-import "math"
+import (
+	"math"
+	"math/cmplx"
+
+	"gonum.org/v1/tools/autofd/internal/physconst"
+)
 
 const pi = math.Pi
 
+// quarter is a constant expression, folded by autofd.
+const quarter = pi / 4
+
+//autofd:derive
 func F1(x float64) float64 {
 	return x * x
 }
@@ -38,6 +47,7 @@ func F6(x float64) float64 {
 	return 2 + x - x
 }
 
+//autofd:derive DxF7 -d2
 func F7(x float64) float64 {
 	return math.Cos(2 * math.Pi * x)
 }
@@ -50,8 +60,420 @@ func F9(x float64) float64 {
 	return pi * x
 }
 
+func F10(x float64) float64 {
+	a := math.Sin(x)
+	return a * a
+}
+
+func F11(x float64) float64 {
+	var a float64
+	a = 2 * x
+	var b = a + x
+	b *= a
+	return b
+}
+
+func F12(x float64) float64 {
+	const c = 3
+	a, b := x, 2.0
+	a += b
+	return c * a * b
+}
+
+func F13(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func F14(x float64) float64 {
+	switch {
+	case x < 0:
+		return -x
+	default:
+
+		return x
+	}
+}
+
+func F15(x float64) float64 {
+	a := x * x
+	switch {
+	case a > 4:
+		a = 4
+	case a < 1:
+		a = 2 * a
+	}
+	if b := a - x; b > 0 {
+		return b
+	} else if b < -1 {
+		return -b * x
+	} else {
+		return a
+	}
+}
+
+func F16(x float64) float64 {
+	a := math.Sin(x)
+	switch n := 2.0; math.Floor(a * n) {
+	case -2, -1:
+		a *= x
+		fallthrough
+	case 0:
+		a += 1
+	}
+	return a
+}
+
+var coeffs = []float64{1, 2, 3, 4}
+
+func F17(x float64) float64 {
+	s := 0.0
+	for i := 0; i < len(coeffs); i++ {
+		s += coeffs[i] * math.Pow(x, float64(i))
+	}
+	return s
+}
+
+func F18(x float64) float64 {
+	p := 1.0
+	for i, c := range coeffs {
+		if i == 2 {
+			continue
+		}
+		p *= c*x + 1
+	}
+	return p
+}
+
+func F19(x float64) float64 {
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			return -x
+		}
+	}
+	return x
+}
+
+func F20(x float64) float64 {
+	a := x
+	n := 0
+outer:
+	for a < 10 {
+		for t := 0.5; t < 2; t *= 2 {
+			a += t * x
+			n++
+			if n > 20 {
+				break outer
+			}
+		}
+	}
+	return a
+}
+
+//autofd:derive -wrt y
+func F21(x float64, n int, y float64) float64 {
+	return math.Pow(x, float64(n)) * y
+}
+
+func sq(x float64) float64 {
+	return x * x
+}
+
+func cube(x float64) float64 {
+	return x * sq(x)
+}
+
+func poly(x float64, n int) float64 {
+	s := 0.0
+	for i := 0; i <= n; i++ {
+		s += math.Pow(x, float64(i))
+	}
+	return s
+}
+
+func F22(x float64) float64 {
+	return cube(math.Sin(x)) + poly(sq(x), 3)
+}
+
+//autofd:derive -mode symbolic
+func F23(x float64) float64 {
+	y := sq(x) + 1
+	if y > 2 {
+		return math.Log(y) / y
+	}
+	return math.Sqrt(y)
+}
+
+func F24(x float64) float64 {
+	a := math.Sin(x)*math.Sin(x) + math.Sin(x)/(x*x+1)
+	return a * math.Exp(x*x+1) * math.Exp(x*x+1)
+}
+
+func F25(x float64) float64 {
+	return math.Log1p(x*x) + math.Expm1(x) - math.Log2(1+x*x) + math.Log10(2+x) + math.Cbrt(x)
+}
+
+func F26(x float64) float64 {
+	return math.Erf(x)*math.Erfc(x/2) + math.Gamma(x+3)
+}
+
+func F27(x float64) float64 {
+	l, _ := math.Lgamma(x*x + 1)
+	return math.Atan2(x, 2-x) + math.Hypot(x, 3*x+1)*l
+}
+
+//autofd:derive DxF28 -d2 -subgradient left
+func F28(x float64) float64 {
+	relu := math.Max(0, x*x-0.5)
+	return relu*relu + math.Max(0, 1-x) + math.Min(x, 2*x-1)
+}
+
+func F29(x float64) float64 {
+	return math.Floor(x)*x + math.Ceil(x*x) + math.Trunc(2*x)*math.Sin(x) + math.Mod(x*x, 1.5)
+}
+
+func F30(x float64) float64 {
+	return x*sigmoid(2*x+1) + softplus(x*x)
+}
+
+func F31(x float64) float64 {
+	return sigmoidDeriv(x) / x
+}
+
+func F32(x float64) float64 {
+	return physconst.Boltzmann*x*1e23 + physconst.Scale*math.Sin(x) + physconst.Temperatures[1]/(x*x+1)
+}
+
+func F34(x float64) float64 {
+	const k = 1 << 3
+	return pi/2*math.Sin(x) + k/3*x + 1/2*x*x + float64(k)/3*math.Exp(x/(1<<2)) + 'a'*x + quarter*x
+}
+
+func F35(x float64, n int) float64 {
+	return float64(n)*x*x + float64(n<<2)/x + float64(len(coeffs)-1)*math.Cos(float64(2*n)*x)
+}
+
+func F36(x float64) float64 {
+	ys := []float64{x, x * x, 3}
+	var zs = [2]float64{1: math.Sin(x)}
+	return ys[1]*ys[0] + ys[2] + zs[1]*float64(len(ys))
+}
+
+func F37(x float64) float64 {
+	s := 0.0
+	for t := x; t < 10; t++ {
+		s += t
+	}
+	return s
+}
+
+func F38(x float64) float64 {
+	s := 0.0
+	for i, v := range []float64{x, 2 * x} {
+		s += float64(i+1) * v * v
+	}
+	return s
+}
+
+func F39(x float64) float64 {
+	n := 3
+	return float64(n/2)*x + float64(n)/2*x*x
+}
+
+func F40(x float64) float64 {
+	a := 2.0
+	b := math.Sqrt(a)
+	c := a * b
+	var ys = []float64{a, b}
+	return c*x*x + ys[1]*x
+}
+
+// sigmoid is the logistic function. Its derivatives are declared by rule
+// directives: calls to sigmoid are derived with sigmoidDeriv, and calls to
+// sigmoidDeriv with sigmoidDeriv2.
+//
+//autofd:rule sigmoid deriv=sigmoidDeriv
+//autofd:rule sigmoidDeriv deriv=sigmoidDeriv2
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+func sigmoidDeriv(x float64) float64 {
+	s := sigmoid(x)
+	return s * (1 - s)
+}
+
+func sigmoidDeriv2(x float64) float64 {
+	s := sigmoid(x)
+	return s * (1 - s) * (1 - 2*s)
+}
+
+//autofd:rule softplus deriv=sigmoid
+func softplus(x float64) float64 {
+	return math.Log1p(math.Exp(x))
+}
+
+//autofd:derive -mode reverse
+func G1(x []float64) float64 {
+	return x[0]*x[0] + 3*x[0]*x[1] + math.Sin(x[1])
+}
+
+func G2(x []float64) float64 {
+	s := 0.0
+	for i, v := range x {
+		s += float64(i+1) * v * v
+	}
+	return s
+}
+
+func G3(x []float64) float64 {
+	s := 0.0
+	for i := 0; i < len(x)-1; i++ {
+		a := 1 - x[i]
+		b := x[i+1] - x[i]*x[i]
+		s += a*a + 100*b*b
+	}
+	return s
+}
+
+func G4(x []float64) float64 {
+	return sq(x[0]) * cube(x[1])
+}
+
+func G5(x []float64) float64 {
+	return math.Atan2(x[0], x[1]) + math.Hypot(x[1], x[2])*math.Erf(x[0])
+}
+
+func G6(x []float64) float64 {
+	return math.Max(0, x[0]*x[1]) + math.Min(x[1], x[2]) + math.Mod(x[2], x[3])
+}
+
+func G7(x []float64) float64 {
+	return softplus(x[0]*x[1]) + sigmoid(x[2])*x[3]
+}
+
+func G8(xd []float64) float64 {
+	fn := 0.0
+	for i, v := range xd {
+		fn += float64(i+1) * v * xd[0]
+	}
+	return fn
+}
+
+func G9(n []float64) float64 {
+	return n[0] * n[1] * math.Exp(n[1])
+}
+
+func G10(tape []float64) float64 {
+	row := tape[0] * tape[1]
+	return row + math.Sin(tape[1])
+}
+
+func J1(dst, x []float64) {
+	dst[0] = x[0] * x[1]
+	dst[1] = math.Sin(x[0]) + x[1]*x[1]
+	dst[2] = math.Exp(x[0] - x[1])
+}
+
+func J2(dst, x []float64) {
+	for i := range dst {
+		dst[i] = 0
+		for j, v := range x {
+			dst[i] += float64(i+j+1) * v * v
+		}
+	}
+}
+
+func J3(dst, n []float64) {
+	m := float64(len(n))
+	dst[0] = m * n[0] * n[1]
+	dst[1] = math.Sin(n[0]) - n[1]
+}
+
+func S1(x float32) float32 {
+	return 3*x*x + 1/x
+}
+
+func S2(x float32) float32 {
+	a := float32(math.Sin(float64(x)))
+	if a > 0.5 {
+		a *= 2
+	}
+	return a * float32(math.Exp(float64(a)))
+}
+
+func S3(x, y float32) float32 {
+	if x > y {
+		return x * y
+	}
+	return float32(math.Sqrt(float64(x*x + y)))
+}
+
+func C1(z complex128) complex128 {
+	return z*z + 2i*z
+}
+
+func C2(z complex128) complex128 {
+	return cmplx.Exp(z) / cmplx.Sqrt(z)
+}
+
+func C3(z complex128) complex128 {
+	w := cmplx.Sin(z)
+	w *= 2 * math.Pi
+	return w * cmplx.Atan(z)
+}
+
+func C4(z complex128, n int) complex128 {
+	return cmplx.Pow(z, complex(float64(n), 0)) - csq(z)
+}
+
+func csq(z complex128) complex128 {
+	return z * z
+}
+
+func P1[T ~float32 | ~float64](x T) T {
+	return 3*x*x + T(math.Sin(float64(x)))
+}
+
+func P2[T ~float32 | ~float64](x T, n int) T {
+	s := T(0)
+	for i := 0; i < n; i++ {
+		s += psq(x) / T(i+1)
+	}
+	return s
+}
+
+func P3[T ~float64](x []T) T {
+	var s T
+	for i := range x {
+		s += psq[T](x[i])
+	}
+	return s
+}
+
+func psq[T ~float32 | ~float64](x T) T {
+	return x * x
+}
+
+//autofd:derive
+var V1 = func(x float64) float64 {
+	return x * math.Exp(x)
+}
+
+var V2 = func(x float64, n int, y float64) float64 {
+	return V1(x) * vsq(y) / float64(n)
+}
+
+var vsq = func(x float64) float64 {
+	return x * x
+}
+
 type T1 struct{}
 
+//autofd:derive
+//autofd:derive DxF -order 3
 func (T1) F(x float64) float64 {
 	return 2*x + 3*x*x + 4*math.Pow(x, 3)
 }
@@ -78,32 +500,45 @@ func ErrF5(x float64) (o float64) {
 	return
 }
 
-func ErrF6(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
+func ErrF9(x float64) float64 {
+	x = 2 * x
 	return x
 }
 
-func ErrF7(x float64) float64 {
-	switch {
-	case x < 0:
-		return -x
-	default:
+func ErrF10(x float64) float64 {
+	return float64(float32(x))
+}
 
-		return x
-	}
+func ErrJ1(dst, x []float64) {
+	x[0] = 1
+	dst[0] = x[0]
 }
 
-func ErrF8(x float64) float64 {
-	for i := 0; i < 10; i++ {
-		if i == 5 {
-			return -x
-		}
-	}
-	return x
+func ErrF11(x float64) float64 {
+	return split(x) * x
+}
+
+func ErrF12(x float64) float64 {
+	y := float32(x)
+	return float64(y * y)
+}
+
+func ErrF13(x float64) float64 {
+	p := &x
+	return *p * *p
+}
+
+func split(x float64) float64 {
+	a, b := math.Modf(x)
+	return a + b
 }
 
+func ErrC1(z complex128) complex128 {
+	return complex(cmplx.Abs(z), 0) * z
+}
+
+var ErrV1 = 2.0
+
 type ErrT1 struct {
 	F float64
 }
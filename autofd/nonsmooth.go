@@ -0,0 +1,126 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autofd
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// name returns the name of the subgradient, as used by directives
+// and generated function names.
+func (s Subgradient) name() string {
+	switch s {
+	case AverageSubgradient:
+		return "average"
+	case LeftSubgradient:
+		return "left"
+	case RightSubgradient:
+		return "right"
+	case ZeroSubgradient:
+		return "zero"
+	}
+	return fmt.Sprintf("Subgradient(%d)", int(s))
+}
+
+// isStep returns whether the provided expression is a call to a piecewise
+// constant function of the math package, whose derivatives are zero.
+func (g *generator) isStep(expr ast.Expr) bool {
+	name, _ := g.mathFunc(expr)
+	switch name {
+	case "Ceil", "Floor", "Trunc":
+		return true
+	}
+	return false
+}
+
+// isKink returns whether the provided expression is a call to math.Max
+// or math.Min, which are not differentiable where their arguments are equal.
+func (g *generator) isKink(expr ast.Expr) bool {
+	name, _ := g.mathFunc(expr)
+	return name == "Max" || name == "Min"
+}
+
+// kink generates a call to the function selecting the maximum or minimum
+// of dual numbers, whose derivatives at kinks follow the subgradient policy.
+func (g *generator) kink(call *ast.CallExpr) {
+	name, _ := g.mathFunc(call)
+	if g.mode == Reverse && (g.subgrad == LeftSubgradient || g.subgrad == RightSubgradient) {
+		g.err = fmt.Errorf("reverse mode %s subgradients of math.%s not supported", g.subgrad.name(), name)
+		return
+	}
+	g.helpers.kinks = append(g.helpers.kinks, name)
+	g.printf("%s(", g.kinkName(name))
+	g.expr(call.Args[0])
+	g.printf(", ")
+	g.expr(call.Args[1])
+	g.printf(")")
+}
+
+// kinkName returns the name of the function selecting the maximum or minimum
+// of dual numbers with the subgradient policy of the generator.
+func (g *generator) kinkName(name string) string {
+	return g.ruleName(name) + "_" + g.subgrad.name()
+}
+
+// kinkFunc generates the function selecting the maximum, or the minimum,
+// of two dual numbers. Away from kinks, the number with the greatest, or
+// smallest, real part is returned.
+//
+// For left and right subgradients, ties are broken by comparing the
+// derivatives of the numbers in increasing order, which orders the numbers
+// as their values just after the kink. The odd derivatives are negated for
+// left subgradients, ordering the numbers as their values just before it.
+func (g *generator) kinkFunc(name string) {
+	dpkg := g.dpkg()
+	g.printf("\nfunc %s(u, v %s.Number) %s.Number {\n", g.kinkName(name), dpkg, dpkg)
+
+	// Min selects the number that Max does not select.
+	u, v := "u", "v"
+	if name == "Min" {
+		u, v = v, u
+	}
+
+	switch g.subgrad {
+	case LeftSubgradient, RightSubgradient:
+		left := g.subgrad == LeftSubgradient
+		cmp := ">"
+		if left {
+			cmp = "<"
+		}
+		switch g.order {
+		case 1:
+			g.printf("\tif d := dual.Sub(u, v); d.Real > 0 || d.Real == 0 && d.Emag %s 0 {\n", cmp)
+		case 2:
+			g.printf("\tif d := hyperdual.Sub(u, v); d.Real > 0 || d.Real == 0 && (d.E1mag %s 0 || d.E1mag == 0 && d.E1E2mag > 0) {\n", cmp)
+		default:
+			if left {
+				g.printf("\tfor k, d := range taylor.Sub(u, v) {\n")
+				g.printf("\t\tif k%%2 == 1 {\n\t\t\td = -d\n\t\t}\n")
+			} else {
+				g.printf("\tfor _, d := range taylor.Sub(u, v) {\n")
+			}
+			g.printf("\t\tswitch {\n\t\tcase d > 0:\n\t\t\treturn %s\n\t\tcase d < 0:\n\t\t\treturn %s\n\t\t}\n\t}\n", u, v)
+			g.printf("\treturn %s\n}\n", u)
+			return
+		}
+		g.printf("\t\treturn %s\n\t}\n\treturn %s\n}\n", u, v)
+	default:
+		real := g.realPart()
+		g.printf("\tswitch {\n\tcase u%s > v%s:\n\t\treturn %s\n\tcase u%s < v%s:\n\t\treturn %s\n\t}\n",
+			real, real, u, real, real, v,
+		)
+		switch g.subgrad {
+		case ZeroSubgradient:
+			g.printf("\treturn ")
+			g.open()
+			g.printf("u%s", real)
+			g.close()
+			g.printf("\n}\n")
+		default:
+			g.printf("\treturn %s.Scale(0.5, %s.Add(u, v))\n}\n", dpkg, dpkg)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autofd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// mathRule is the derivative rule of a function of the math package
+// without dual and hyperdual number equivalents.
+// Derivatives are expressions of the real parts x, and y for functions
+// of two variables, of the arguments. Second derivatives may refer to
+// the first derivatives.
+type mathRule struct {
+	d1 []string // first partial derivatives: dx, dy.
+	d2 []string // second partial derivatives: dxx, dxy, dyy, nil if all zero.
+
+	// sign is whether the function also returns the sign of its value,
+	// as math.Lgamma.
+	sign bool
+
+	// higher is whether the taylor and adjoint packages provide
+	// the function, for higher order and reverse mode derivatives.
+	higher bool
+}
+
+// mathRules maps the functions of the math package without dual and hyperdual
+// number equivalents to their derivative rule.
+var mathRules = map[string]mathRule{
+	"Atan2": {
+		d1:     []string{"y / (x*x + y*y)", "-x / (x*x + y*y)"},
+		d2:     []string{"2 * dx * dy", "dy*dy - dx*dx", "-2 * dx * dy"},
+		higher: true,
+	},
+	"Cbrt": {
+		d1:     []string{"1 / (3 * math.Cbrt(x*x))"},
+		d2:     []string{"-2 * dx / (3 * x)"},
+		higher: true,
+	},
+	"Erf": {
+		d1:     []string{"2 / math.SqrtPi * math.Exp(-x*x)"},
+		d2:     []string{"-2 * x * dx"},
+		higher: true,
+	},
+	"Erfc": {
+		d1:     []string{"-2 / math.SqrtPi * math.Exp(-x*x)"},
+		d2:     []string{"-2 * x * dx"},
+		higher: true,
+	},
+	"Expm1": {
+		d1:     []string{"math.Exp(x)"},
+		d2:     []string{"dx"},
+		higher: true,
+	},
+	"Gamma": {
+		d1: []string{"math.Gamma(x) * mathext.Digamma(x)"},
+		d2: []string{"dx*mathext.Digamma(x) + math.Gamma(x)*mathext.Zeta(2, x)"},
+	},
+	"Hypot": {
+		d1:     []string{"x / math.Hypot(x, y)", "y / math.Hypot(x, y)"},
+		d2:     []string{"dy * dy / math.Hypot(x, y)", "-dx * dy / math.Hypot(x, y)", "dx * dx / math.Hypot(x, y)"},
+		higher: true,
+	},
+	"Lgamma": {
+		d1:   []string{"mathext.Digamma(x)"},
+		d2:   []string{"mathext.Zeta(2, x)"},
+		sign: true,
+	},
+	"Log10": {
+		d1:     []string{"1 / (x * math.Ln10)"},
+		d2:     []string{"-dx / x"},
+		higher: true,
+	},
+	"Log1p": {
+		d1:     []string{"1 / (1 + x)"},
+		d2:     []string{"-dx * dx"},
+		higher: true,
+	},
+	"Log2": {
+		d1:     []string{"1 / (x * math.Ln2)"},
+		d2:     []string{"-dx / x"},
+		higher: true,
+	},
+	"Mod": {
+		// The truncated quotient x/y is held constant.
+		d1:     []string{"1.0", "-math.Trunc(x / y)"},
+		higher: true,
+	},
+}
+
+// isRule returns whether the provided expression is a call to a function
+// of the math package derived with a rule of mathRules.
+func (g *generator) isRule(expr ast.Expr) bool {
+	name, ok := g.mathFunc(expr)
+	if !ok {
+		return false
+	}
+	_, ok = mathRules[name]
+	return ok
+}
+
+// rule generates a call to the function applying the derivative rule
+// of the named function of the math package to dual numbers.
+// For higher order and reverse mode derivatives, the function provided
+// by the dual number package is called instead.
+func (g *generator) rule(call *ast.CallExpr) {
+	name := call.Fun.(*ast.SelectorExpr).Sel.Name
+	switch {
+	case g.order <= 2 && g.mode != Reverse:
+		g.helpers.rules = append(g.helpers.rules, name)
+		g.printf("%s(", g.ruleName(name))
+	case !mathRules[name].higher && g.mode == Reverse:
+		g.err = fmt.Errorf("reverse mode derivatives of math.%s not supported", name)
+		return
+	case !mathRules[name].higher:
+		g.err = fmt.Errorf("derivatives of order %d of math.%s not supported", g.order, name)
+		return
+	default:
+		g.printf("%s.%s(", g.dpkg(), name)
+	}
+	for i, arg := range call.Args {
+		if i > 0 {
+			g.printf(", ")
+		}
+		g.expr(arg)
+	}
+	g.printf(")")
+}
+
+// ruleName returns the name of the function applying the derivative rule
+// of the named function of the math package to dual numbers.
+func (g *generator) ruleName(name string) string {
+	return g.dpkg() + "_math_" + name
+}
+
+// ruleFunc generates the function, with the provided name, applying the
+// provided derivative rule of the function fct to dual or hyperdual numbers,
+// or to numbers recording their operations for first derivatives in reverse
+// mode. The derivatives of the function with respect to the real parts of
+// its arguments are propagated to the dual parts by the chain rule.
+func (g *generator) ruleFunc(name, fct string, rule mathRule) {
+	var (
+		dpkg    = g.dpkg()
+		params  = []string{"u", "v"}[:len(rule.d1)]
+		reals   = []string{"x", "y"}[:len(rule.d1)]
+		partial = []string{"dx", "dy"}[:len(rule.d1)]
+		results = dpkg + ".Number"
+	)
+	if rule.sign {
+		results = "(" + results + ", int)"
+	}
+	g.printf("\nfunc %s(%s %s.Number) %s {\n", name, strings.Join(params, ", "), dpkg, results)
+	real := make([]string, len(params))
+	for i, p := range params {
+		real[i] = p + ".Real"
+	}
+	g.printf("\t%s := %s\n", strings.Join(reals, ", "), strings.Join(real, ", "))
+	value := fmt.Sprintf("%s(%s)", fct, strings.Join(reals, ", "))
+	if rule.sign {
+		g.printf("\tv, sign := %s\n", value)
+		value = "v"
+	}
+	for i, d := range rule.d1 {
+		g.printf("\t%s := %s\n", partial[i], g.mathRule(d))
+	}
+
+	// dual returns the first order term of the result for the named
+	// dual part of the arguments.
+	dual := func(part string) string {
+		terms := make([]string, len(params))
+		for i, p := range params {
+			terms[i] = partial[i] + "*" + p + "." + part
+		}
+		return strings.Join(terms, " + ")
+	}
+
+	var ret string
+	switch {
+	case g.mode == Reverse:
+		// The operation is recorded as the scaling of the argument
+		// by its derivative, with the value of the function.
+		w := dpkg + ".Scale(dx, u)"
+		if len(params) == 2 {
+			w = fmt.Sprintf("%s.Add(%s, %s.Scale(dy, v))", dpkg, w, dpkg)
+		}
+		g.printf("\tw := %s\n\tw.Real = %s\n", w, value)
+		ret = "w"
+	case g.order == 1:
+		ret = fmt.Sprintf("%s.Number{Real: %s, Emag: %s}", dpkg, value, dual("Emag"))
+	default:
+		second := []string{"dxx", "dxy", "dyy"}
+		if len(params) == 1 {
+			second = second[:1]
+		}
+		for i, d := range rule.d2 {
+			g.printf("\t%s := %s\n", second[i], g.mathRule(d))
+		}
+		// The second order term adds the second derivatives
+		// weighted by the products of the dual parts.
+		e1e2 := dual("E1E2mag")
+		switch {
+		case rule.d2 == nil:
+			// ok
+		case len(params) == 2:
+			e1e2 += " + dxx*u.E1mag*u.E2mag + dxy*(u.E1mag*v.E2mag+v.E1mag*u.E2mag) + dyy*v.E1mag*v.E2mag"
+		default:
+			e1e2 += " + dxx*u.E1mag*u.E2mag"
+		}
+		ret = fmt.Sprintf("%s.Number{\n\t\tReal:    %s,\n\t\tE1mag:   %s,\n\t\tE2mag:   %s,\n\t\tE1E2mag: %s,\n\t}",
+			dpkg, value, dual("E1mag"), dual("E2mag"), e1e2,
+		)
+	}
+	if rule.sign {
+		ret += ", sign"
+	}
+	g.printf("\treturn %s\n}\n", ret)
+}
+
+// derivRule generates the version operating on dual numbers of the provided
+// same-package function, applying the derivative declared by a rule
+// directive. Second derivatives are those declared for the derivative.
+func (g *generator) derivRule(fct *types.Func) {
+	d1 := g.helpers.derivs[fct]
+	rule := mathRule{d1: []string{d1.Name() + "(x)"}}
+	switch {
+	case g.order > 2:
+		g.err = fmt.Errorf("derivatives of order %d of %s not supported", g.order, fct.Name())
+		return
+	case g.order == 2:
+		d2, ok := g.helpers.derivs[d1]
+		if !ok {
+			g.err = fmt.Errorf("second derivatives of %s not supported: no rule for %s", fct.Name(), d1.Name())
+			return
+		}
+		rule.d2 = []string{d2.Name() + "(x)"}
+	}
+	g.ruleFunc(g.helperName(fct), fct.Name(), rule)
+}
+
+// mathRule returns the provided derivative rule, referring to the math
+// package under the name given by mathName.
+func (g *generator) mathRule(d string) string {
+	name := g.mathName()
+	if name == "math" {
+		return d
+	}
+	return strings.ReplaceAll(d, "math.", name+".")
+}
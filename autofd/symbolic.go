@@ -0,0 +1,950 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autofd
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// values maps local variables to the expressions of their values,
+// in terms of the parameters of the derived function.
+type values map[types.Object]ast.Expr
+
+// clone returns a copy of the values.
+func (vs values) clone() values {
+	o := make(values, len(vs))
+	for k, v := range vs {
+		o[k] = v
+	}
+	return o
+}
+
+// symStmts generates the symbolic derivatives of the values returned by the
+// provided statements, and returns whether they end with a return statement.
+// Local variables are inlined into the expressions of the returned values,
+// and if and switch statements are reproduced with their conditions inlined.
+func (g *generator) symStmts(stmts []ast.Stmt, vs values) bool {
+	for _, stmt := range stmts {
+		if g.err != nil {
+			return true
+		}
+		switch stmt := stmt.(type) {
+		default:
+			g.err = fmt.Errorf("invalid statement type in symbolic mode: %T", stmt)
+			return true
+		case *ast.EmptyStmt:
+			// no op
+		case *ast.ReturnStmt:
+			g.symReturn(g.inline(stmt.Results[0], vs))
+			return true
+		case *ast.AssignStmt, *ast.IncDecStmt, *ast.DeclStmt:
+			g.bind(stmt, vs)
+		case *ast.BlockStmt:
+			if g.symStmts(stmt.List, vs) {
+				return true
+			}
+		case *ast.IfStmt:
+			g.tab()
+			g.symIf(stmt, vs)
+			g.printf("\n")
+		case *ast.SwitchStmt:
+			g.tab()
+			g.symSwitch(stmt, vs)
+			g.printf("\n")
+		}
+	}
+	return false
+}
+
+// symReturn generates the return statement of the symbolic derivatives
+// of the provided expression, up to the order of the generator.
+func (g *generator) symReturn(expr ast.Expr) {
+	g.tab()
+	g.printf("return ")
+	for i := 1; i <= g.order && g.err == nil; i++ {
+		if i > 1 {
+			g.printf(", ")
+		}
+		expr = simplify(g.diff(expr))
+		g.symExpr(expr)
+	}
+	g.printf("\n")
+}
+
+// symBranch generates the provided branch of an if or switch statement.
+// Branches must end with a return statement: values assigned in a branch
+// are not visible after the branch.
+func (g *generator) symBranch(stmts []ast.Stmt, vs values) {
+	g.indent++
+	if !g.symStmts(stmts, vs.clone()) && g.err == nil {
+		g.err = fmt.Errorf("branches must end with a return statement in symbolic mode")
+	}
+	g.indent--
+}
+
+// symIf generates code for the provided if statement, with its condition inlined.
+func (g *generator) symIf(stmt *ast.IfStmt, vs values) {
+	vs = vs.clone()
+	if stmt.Init != nil {
+		g.bind(stmt.Init, vs)
+	}
+	g.printf("if ")
+	g.symExpr(g.inline(stmt.Cond, vs))
+	g.printf(" {\n")
+	g.symBranch(stmt.Body.List, vs)
+	g.tab()
+	g.printf("}")
+	switch els := stmt.Else.(type) {
+	case nil:
+		// no op
+	case *ast.IfStmt:
+		g.printf(" else ")
+		g.symIf(els, vs)
+	case *ast.BlockStmt:
+		g.printf(" else {\n")
+		g.symBranch(els.List, vs)
+		g.tab()
+		g.printf("}")
+	}
+}
+
+// symSwitch generates code for the provided switch statement, with its tag
+// and case expressions inlined.
+func (g *generator) symSwitch(stmt *ast.SwitchStmt, vs values) {
+	vs = vs.clone()
+	if stmt.Init != nil {
+		g.bind(stmt.Init, vs)
+	}
+	g.printf("switch ")
+	if stmt.Tag != nil {
+		g.symExpr(g.inline(stmt.Tag, vs))
+		g.printf(" ")
+	}
+	g.printf("{\n")
+	for _, cc := range stmt.Body.List {
+		cc := cc.(*ast.CaseClause)
+		g.tab()
+		switch cc.List {
+		case nil:
+			g.printf("default:\n")
+		default:
+			g.printf("case ")
+			for i, expr := range cc.List {
+				if i > 0 {
+					g.printf(", ")
+				}
+				g.symExpr(g.inline(expr, vs))
+			}
+			g.printf(":\n")
+		}
+		g.symBranch(cc.Body, vs)
+	}
+	g.tab()
+	g.printf("}")
+}
+
+// bind records the values assigned by the provided assignment,
+// increment or declaration statement.
+func (g *generator) bind(stmt ast.Stmt, vs values) {
+	if g.err != nil {
+		return
+	}
+
+	var (
+		lhs  []*ast.Ident
+		rhs  []ast.Expr
+		zero = &ast.BasicLit{Kind: token.INT, Value: "0"}
+	)
+	switch stmt := stmt.(type) {
+	default:
+		g.err = fmt.Errorf("invalid statement type in symbolic mode: %T", stmt)
+		return
+	case *ast.AssignStmt:
+		if len(stmt.Lhs) != len(stmt.Rhs) {
+			g.err = fmt.Errorf("can not handle multi-valued assignments")
+			return
+		}
+		for i := range stmt.Lhs {
+			id, ok := stmt.Lhs[i].(*ast.Ident)
+			if !ok {
+				g.err = fmt.Errorf("can not assign to %s", types.ExprString(stmt.Lhs[i]))
+				return
+			}
+			lhs = append(lhs, id)
+			switch stmt.Tok {
+			case token.DEFINE, token.ASSIGN:
+				rhs = append(rhs, stmt.Rhs[i])
+			default:
+				op, ok := assignOps[stmt.Tok]
+				if !ok {
+					g.err = fmt.Errorf("invalid assignment token %v", stmt.Tok)
+					return
+				}
+				rhs = append(rhs, &ast.BinaryExpr{X: id, Op: op, Y: stmt.Rhs[i]})
+			}
+		}
+	case *ast.IncDecStmt:
+		id, ok := stmt.X.(*ast.Ident)
+		if !ok {
+			g.err = fmt.Errorf("can not assign to %s", types.ExprString(stmt.X))
+			return
+		}
+		op := token.ADD
+		if stmt.Tok == token.DEC {
+			op = token.SUB
+		}
+		lhs = append(lhs, id)
+		rhs = append(rhs, &ast.BinaryExpr{X: id, Op: op, Y: &ast.BasicLit{Kind: token.INT, Value: "1"}})
+	case *ast.DeclStmt:
+		decl, ok := stmt.Decl.(*ast.GenDecl)
+		if !ok {
+			g.err = fmt.Errorf("invalid declaration: %#v (%T)", stmt.Decl, stmt.Decl)
+			return
+		}
+		if decl.Tok != token.VAR {
+			// Local constants are inlined from their constant value.
+			return
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ValueSpec)
+			if len(spec.Values) != 0 && len(spec.Values) != len(spec.Names) {
+				g.err = fmt.Errorf("can not handle multi-valued declarations")
+				return
+			}
+			for i, name := range spec.Names {
+				lhs = append(lhs, name)
+				switch {
+				case len(spec.Values) != 0:
+					rhs = append(rhs, spec.Values[i])
+				case g.isNumeric(name):
+					rhs = append(rhs, zero)
+				default:
+					g.err = fmt.Errorf("can not inline %s", name.Name)
+					return
+				}
+			}
+		}
+	}
+
+	// Values are inlined before any of them is assigned.
+	vals := make([]ast.Expr, len(rhs))
+	for i, expr := range rhs {
+		vals[i] = g.inline(expr, vs)
+	}
+	for i, id := range lhs {
+		obj := g.object(id)
+		switch {
+		case obj == nil:
+			// blank identifier.
+		case obj == g.in:
+			g.err = fmt.Errorf("can not assign to %s", id.Name)
+		case obj.Parent() == g.pkg.Types.Scope():
+			g.err = fmt.Errorf("can not assign to package-level variable %s", id.Name)
+		default:
+			vs[obj] = vals[i]
+		}
+	}
+}
+
+// isNumeric returns whether the provided identifier denotes a number.
+func (g *generator) isNumeric(id *ast.Ident) bool {
+	basic, ok := g.typ(g.object(id).Type()).Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsNumeric != 0
+}
+
+// inline returns the provided expression with local variables replaced
+// by their values, local constants and constant expressions by their constant
+// value, and calls to same-package functions by the value they return.
+func (g *generator) inline(expr ast.Expr, vs values) ast.Expr {
+	if g.err != nil {
+		return expr
+	}
+	switch expr.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		// Named constants are kept as written.
+	default:
+		// Literals are folded too, so that they are simplified with
+		// the exact value the compiler gives them, as 0 for 1 / 2.
+		if v := g.constValue(expr); v != nil {
+			return numLit(v)
+		}
+	}
+
+	switch expr := expr.(type) {
+	default:
+		g.err = fmt.Errorf("invalid expr type: %#v (%T)", expr, expr)
+		return expr
+	case *ast.BasicLit, *ast.SelectorExpr:
+		return expr
+	case *ast.Ident:
+		obj := g.object(expr)
+		if v, ok := vs[obj]; ok {
+			return v
+		}
+		if c, ok := obj.(*types.Const); ok && c.Parent() != g.pkg.Types.Scope() && c.Parent() != types.Universe {
+			if v := g.constValue(expr); v != nil {
+				return numLit(v)
+			}
+			return numLit(constant.ToFloat(c.Val()))
+		}
+		if arg := g.typeArg(expr); arg != nil {
+			return ast.NewIdent(g.typeString(arg))
+		}
+		return expr
+	case *ast.ParenExpr:
+		return g.inline(expr.X, vs)
+	case *ast.UnaryExpr:
+		return &ast.UnaryExpr{Op: expr.Op, X: g.inline(expr.X, vs)}
+	case *ast.BinaryExpr:
+		return &ast.BinaryExpr{X: g.inline(expr.X, vs), Op: expr.Op, Y: g.inline(expr.Y, vs)}
+	case *ast.IndexExpr:
+		return &ast.IndexExpr{X: g.inline(expr.X, vs), Index: g.inline(expr.Index, vs)}
+	case *ast.CallExpr:
+		args := make([]ast.Expr, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = g.inline(arg, vs)
+		}
+		if g.pkg.TypesInfo.Types[expr.Fun].IsType() {
+			if g.isFloat(expr.Args[0]) {
+				return args[0]
+			}
+			// Conversions from integers are piecewise constant.
+			typ := g.typ(g.pkg.TypesInfo.TypeOf(expr))
+			return &ast.CallExpr{Fun: ast.NewIdent(g.typeString(typ)), Args: args}
+		}
+		if fct := g.callee(expr); fct != nil {
+			if _, ok := g.helpers.derivs[fct]; ok {
+				// Functions with a declared derivative are not inlined.
+				return &ast.CallExpr{Fun: expr.Fun, Args: args}
+			}
+			return g.inlineCall(fct, expr, args)
+		}
+		return &ast.CallExpr{Fun: g.inline(expr.Fun, vs), Args: args}
+	}
+}
+
+// inlineCall returns the value returned by the provided same-package function
+// called with the provided inlined arguments.
+// The function must be made of assignments and declarations followed by
+// a return statement.
+func (g *generator) inlineCall(fct *types.Func, expr *ast.CallExpr, args []ast.Expr) ast.Expr {
+	if g.helpers.seen[fct] {
+		g.err = fmt.Errorf("can not inline recursive call to %s", fct.Name())
+		return expr
+	}
+	g.helpers.seen[fct] = true
+	defer delete(g.helpers.seen, fct)
+
+	h := &generator{
+		pkg:     g.pkg,
+		fct:     fct,
+		order:   g.order,
+		mode:    g.mode,
+		scalar:  g.scalar,
+		form:    g.form,
+		targs:   g.infer(fct, expr),
+		helpers: g.helpers,
+	}
+	decl := g.decl(fct)
+	sig := fct.Type().(*types.Signature)
+	if sig.Variadic() || sig.Results().Len() != 1 {
+		g.err = fmt.Errorf("invalid function signature for %s", fct.Name())
+		return expr
+	}
+
+	vs := make(values)
+	for i := 0; i < sig.Params().Len(); i++ {
+		vs[sig.Params().At(i)] = args[i]
+	}
+	for _, stmt := range decl.Body.List {
+		switch stmt := stmt.(type) {
+		case *ast.AssignStmt, *ast.IncDecStmt, *ast.DeclStmt:
+			h.bind(stmt, vs)
+		case *ast.ReturnStmt:
+			if len(stmt.Results) != 1 {
+				h.err = fmt.Errorf("too many return values")
+				break
+			}
+			ret := h.inline(stmt.Results[0], vs)
+			if h.err != nil {
+				break
+			}
+			return ret
+		default:
+			h.err = fmt.Errorf("invalid statement type in symbolic mode: %T", stmt)
+		}
+		if h.err != nil {
+			break
+		}
+	}
+	if h.err == nil {
+		h.err = fmt.Errorf("could not find a return statement")
+	}
+	g.err = fmt.Errorf("could not inline %s: %w", fct.Name(), h.err)
+	return expr
+}
+
+// diff returns the derivative of the provided inlined expression with respect
+// to the seeded variable. The returned expression is not simplified.
+func (g *generator) diff(expr ast.Expr) ast.Expr {
+	if g.err != nil || !g.symDepends(expr) {
+		return numLit(constant.MakeInt64(0))
+	}
+
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		// The seeded variable.
+		return numLit(constant.MakeInt64(1))
+	case *ast.UnaryExpr:
+		switch expr.Op {
+		case token.ADD:
+			return g.diff(expr.X)
+		case token.SUB:
+			return &ast.UnaryExpr{Op: token.SUB, X: g.diff(expr.X)}
+		}
+	case *ast.BinaryExpr:
+		x, y := expr.X, expr.Y
+		switch expr.Op {
+		case token.ADD, token.SUB:
+			return binary(g.diff(x), expr.Op, g.diff(y))
+		case token.MUL:
+			return binary(
+				binary(g.diff(x), token.MUL, y),
+				token.ADD,
+				binary(g.diff(y), token.MUL, x),
+			)
+		case token.QUO:
+			if !g.symDepends(y) {
+				return binary(g.diff(x), token.QUO, y)
+			}
+			return binary(
+				binary(
+					binary(g.diff(x), token.MUL, y),
+					token.SUB,
+					binary(g.diff(y), token.MUL, x),
+				),
+				token.QUO,
+				binary(y, token.MUL, y),
+			)
+		}
+	case *ast.CallExpr:
+		if id, ok := expr.Fun.(*ast.Ident); ok {
+			// Calls to functions with a declared derivative are kept
+			// by inline, and calls to their derivative are created by diff.
+			fct, _ := g.pkg.Types.Scope().Lookup(id.Name).(*types.Func)
+			deriv, ok := g.helpers.derivs[fct]
+			if !ok {
+				break
+			}
+			u := expr.Args[0]
+			return binary(&ast.CallExpr{Fun: ast.NewIdent(deriv.Name()), Args: []ast.Expr{u}}, token.MUL, g.diff(u))
+		}
+		sel, ok := expr.Fun.(*ast.SelectorExpr)
+		if !ok || g.importPath(sel.X) != g.mathPath() {
+			break
+		}
+		switch sel.Sel.Name {
+		case "Pow":
+			return g.diffPow(expr.Args[0], expr.Args[1])
+		case "Atan2":
+			// (v*u' - u*v') / (u*u + v*v)
+			u, v := expr.Args[0], expr.Args[1]
+			return binary(
+				binary(binary(v, token.MUL, g.diff(u)), token.SUB, binary(u, token.MUL, g.diff(v))),
+				token.QUO,
+				binary(binary(u, token.MUL, u), token.ADD, binary(v, token.MUL, v)),
+			)
+		case "Hypot":
+			// (u*u' + v*v') / hypot(u, v)
+			u, v := expr.Args[0], expr.Args[1]
+			return binary(
+				binary(binary(u, token.MUL, g.diff(u)), token.ADD, binary(v, token.MUL, g.diff(v))),
+				token.QUO,
+				expr,
+			)
+		case "Floor", "Ceil", "Trunc":
+			// Piecewise constant.
+			return numLit(constant.MakeInt64(0))
+		case "Mod":
+			// u' - trunc(u/v)*v'
+			u, v := expr.Args[0], expr.Args[1]
+			return binary(
+				g.diff(u),
+				token.SUB,
+				binary(mathCall("Trunc", binary(u, token.QUO, v)), token.MUL, g.diff(v)),
+			)
+		}
+		rule, ok := symRules[sel.Sel.Name]
+		if !ok {
+			break
+		}
+		u := expr.Args[0]
+		return binary(rule(u), token.MUL, g.diff(u))
+	}
+	g.err = fmt.Errorf("can not derive %s symbolically", types.ExprString(parens(expr)))
+	return expr
+}
+
+// diffPow returns the derivative of math.Pow(u, v).
+func (g *generator) diffPow(u, v ast.Expr) ast.Expr {
+	if !g.symDepends(v) {
+		// v * u^(v-1) * u'
+		return binary(
+			binary(v, token.MUL, mathCall("Pow", u, binary(v, token.SUB, numLit(constant.MakeInt64(1))))),
+			token.MUL,
+			g.diff(u),
+		)
+	}
+	// u^v * (v' * log(u) + v * u' / u)
+	return binary(
+		mathCall("Pow", u, v),
+		token.MUL,
+		binary(
+			binary(g.diff(v), token.MUL, mathCall("Log", u)),
+			token.ADD,
+			binary(binary(v, token.MUL, g.diff(u)), token.QUO, u),
+		),
+	)
+}
+
+// symRules maps the functions of the math package that can be derived
+// symbolically to the derivative of the function at u.
+var symRules = map[string]func(u ast.Expr) ast.Expr{
+	"Abs": func(u ast.Expr) ast.Expr {
+		return mathCall("Copysign", numLit(constant.MakeInt64(1)), u)
+	},
+	"Acos": func(u ast.Expr) ast.Expr {
+		return &ast.UnaryExpr{Op: token.SUB, X: binary(one(), token.QUO, mathCall("Sqrt", binary(one(), token.SUB, binary(u, token.MUL, u))))}
+	},
+	"Acosh": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, mathCall("Sqrt", binary(binary(u, token.MUL, u), token.SUB, one())))
+	},
+	"Asin": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, mathCall("Sqrt", binary(one(), token.SUB, binary(u, token.MUL, u))))
+	},
+	"Asinh": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, mathCall("Sqrt", binary(binary(u, token.MUL, u), token.ADD, one())))
+	},
+	"Atan": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, binary(one(), token.ADD, binary(u, token.MUL, u)))
+	},
+	"Atanh": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, binary(one(), token.SUB, binary(u, token.MUL, u)))
+	},
+	"Cos": func(u ast.Expr) ast.Expr {
+		return &ast.UnaryExpr{Op: token.SUB, X: mathCall("Sin", u)}
+	},
+	"Cosh": func(u ast.Expr) ast.Expr {
+		return mathCall("Sinh", u)
+	},
+	"Cbrt": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, binary(numLit(constant.MakeInt64(3)), token.MUL, mathCall("Cbrt", binary(u, token.MUL, u))))
+	},
+	"Erf": func(u ast.Expr) ast.Expr {
+		return binary(binary(numLit(constant.MakeInt64(2)), token.QUO, mathConst("SqrtPi")), token.MUL, mathCall("Exp", &ast.UnaryExpr{Op: token.SUB, X: binary(u, token.MUL, u)}))
+	},
+	"Erfc": func(u ast.Expr) ast.Expr {
+		return binary(binary(numLit(constant.MakeInt64(-2)), token.QUO, mathConst("SqrtPi")), token.MUL, mathCall("Exp", &ast.UnaryExpr{Op: token.SUB, X: binary(u, token.MUL, u)}))
+	},
+	"Exp": func(u ast.Expr) ast.Expr {
+		return mathCall("Exp", u)
+	},
+	"Expm1": func(u ast.Expr) ast.Expr {
+		return mathCall("Exp", u)
+	},
+	"Log": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, u)
+	},
+	"Log10": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, binary(u, token.MUL, mathConst("Ln10")))
+	},
+	"Log1p": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, binary(one(), token.ADD, u))
+	},
+	"Log2": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, binary(u, token.MUL, mathConst("Ln2")))
+	},
+	"Sin": func(u ast.Expr) ast.Expr {
+		return mathCall("Cos", u)
+	},
+	"Sinh": func(u ast.Expr) ast.Expr {
+		return mathCall("Cosh", u)
+	},
+	"Sqrt": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.QUO, binary(numLit(constant.MakeInt64(2)), token.MUL, mathCall("Sqrt", u)))
+	},
+	"Tan": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.ADD, binary(mathCall("Tan", u), token.MUL, mathCall("Tan", u)))
+	},
+	"Tanh": func(u ast.Expr) ast.Expr {
+		return binary(one(), token.SUB, binary(mathCall("Tanh", u), token.MUL, mathCall("Tanh", u)))
+	},
+}
+
+// symDepends returns whether the provided inlined expression depends on
+// the seeded variable.
+func (g *generator) symDepends(expr ast.Expr) bool {
+	dep := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && g.object(id) == g.xvar {
+			dep = true
+		}
+		return !dep
+	})
+	return dep
+}
+
+// symExpr prints the provided inlined expression, parenthesized and formatted.
+func (g *generator) symExpr(expr ast.Expr) {
+	if g.err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	// Expressions are printed and parsed back so that they are formatted
+	// from consistent positions.
+	expr, err := parser.ParseExprFrom(fset, "", types.ExprString(parens(expr)), 0)
+	if err != nil {
+		g.err = fmt.Errorf("could not parse symbolic expression: %w", err)
+		return
+	}
+	if name := g.mathName(); name != "math" {
+		// Derivatives of math functions are built referring to the
+		// math package as math, and are printed referring to it as the
+		// file of the derived function does.
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && id.Name == "math" {
+					id.Name = name
+				}
+			}
+			return true
+		})
+	}
+	buf := new(bytes.Buffer)
+	g.err = printer.Fprint(buf, fset, expr)
+	g.printf("%s", buf)
+}
+
+// simplify returns the provided expression with constants folded and
+// trivial products and sums eliminated.
+func simplify(expr ast.Expr) ast.Expr {
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return simplify(expr.X)
+	case *ast.UnaryExpr:
+		x := simplify(expr.X)
+		switch expr.Op {
+		case token.ADD:
+			return x
+		case token.SUB:
+			if v, ok := numVal(x); ok {
+				return numLit(constant.UnaryOp(token.SUB, v, 0))
+			}
+			if u, ok := x.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+				return u.X
+			}
+		}
+		return &ast.UnaryExpr{Op: expr.Op, X: x}
+	case *ast.CallExpr:
+		args := make([]ast.Expr, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = simplify(arg)
+		}
+		if id, ok := expr.Fun.(*ast.Ident); ok && (id.Name == "float64" || id.Name == "float32") && len(args) == 1 {
+			// Integer values are converted to floating-point values
+			// once folded, as in 1 for float64(3 / 2).
+			if v, ok := numVal(args[0]); ok {
+				return numLit(constant.ToFloat(v))
+			}
+		}
+		if sel, ok := expr.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Pow" && len(args) == 2 {
+			switch {
+			case isNum(args[1], 0):
+				return one()
+			case isNum(args[1], 1):
+				return args[0]
+			}
+		}
+		return &ast.CallExpr{Fun: expr.Fun, Args: args}
+	case *ast.BinaryExpr:
+		return simplifyBinary(simplify(expr.X), expr.Op, simplify(expr.Y))
+	}
+	return expr
+}
+
+// simplifyBinary returns the simplified binary expression of the provided
+// simplified operands.
+func simplifyBinary(x ast.Expr, op token.Token, y ast.Expr) ast.Expr {
+	vx, xok := numVal(x)
+	vy, yok := numVal(y)
+	if xok && yok && !(op == token.QUO && constant.Sign(vy) == 0) {
+		switch op {
+		case token.QUO:
+			if vx.Kind() == constant.Int && vy.Kind() == constant.Int {
+				// Integer literals are divided as the compiler does, as in 1 for 3 / 2.
+				return numLit(constant.BinaryOp(vx, token.QUO_ASSIGN, vy))
+			}
+			return numLit(constant.BinaryOp(vx, op, vy))
+		case token.ADD, token.SUB, token.MUL:
+			return numLit(constant.BinaryOp(vx, op, vy))
+		}
+	}
+	nx, ux := negated(x)
+	ny, uy := negated(y)
+
+	switch op {
+	case token.ADD, token.SUB:
+		switch {
+		case isNum(y, 0):
+			return x
+		case isNum(x, 0) && op == token.ADD:
+			return y
+		case isNum(x, 0):
+			return simplify(&ast.UnaryExpr{Op: token.SUB, X: y})
+		case uy && op == token.ADD:
+			return simplifyBinary(x, token.SUB, ny)
+		case uy:
+			return simplifyBinary(x, token.ADD, ny)
+		}
+		// Collect terms: c1*a ± c2*a = (c1 ± c2)*a.
+		cx, tx := coeff(x)
+		cy, ty := coeff(y)
+		if equal(tx, ty) {
+			return simplifyBinary(numLit(constant.BinaryOp(cx, op, cy)), token.MUL, tx)
+		}
+	case token.MUL:
+		switch {
+		case isNum(x, 0), isNum(y, 0):
+			return numLit(constant.MakeInt64(0))
+		case isNum(x, 1):
+			return y
+		case isNum(y, 1):
+			return x
+		case isNum(x, -1):
+			return simplify(&ast.UnaryExpr{Op: token.SUB, X: y})
+		case yok:
+			// Constant factors come first.
+			return simplifyBinary(y, op, x)
+		case ux:
+			return simplify(&ast.UnaryExpr{Op: token.SUB, X: simplifyBinary(nx, op, y)})
+		case uy:
+			return simplify(&ast.UnaryExpr{Op: token.SUB, X: simplifyBinary(x, op, ny)})
+		}
+		if b, ok := x.(*ast.BinaryExpr); ok && b.Op == token.QUO {
+			switch {
+			case equal(b.Y, y):
+				return b.X
+			case isNum(b.X, 1):
+				return simplifyBinary(y, token.QUO, b.Y)
+			}
+		}
+		if b, ok := y.(*ast.BinaryExpr); ok && b.Op == token.QUO {
+			switch {
+			case equal(b.Y, x):
+				return b.X
+			case isNum(b.X, 1):
+				return simplifyBinary(x, token.QUO, b.Y)
+			}
+		}
+		// Collect constant factors: (c1*a) * (c2*b) = (c1*c2) * (a*b).
+		cx, tx := coeff(x)
+		cy, ty := coeff(y)
+		switch {
+		case xok:
+			if !isValue(cy, 1) {
+				return simplifyBinary(numLit(constant.BinaryOp(vx, token.MUL, cy)), token.MUL, ty)
+			}
+		case !isValue(cx, 1) || !isValue(cy, 1):
+			return simplifyBinary(numLit(constant.BinaryOp(cx, token.MUL, cy)), token.MUL, simplifyBinary(tx, token.MUL, ty))
+		}
+	case token.QUO:
+		switch {
+		case isNum(x, 0):
+			return numLit(constant.MakeInt64(0))
+		case isNum(y, 1):
+			return x
+		case ux:
+			return simplify(&ast.UnaryExpr{Op: token.SUB, X: simplifyBinary(nx, op, y)})
+		case uy:
+			return simplify(&ast.UnaryExpr{Op: token.SUB, X: simplifyBinary(x, op, ny)})
+		case equal(x, y):
+			return numLit(constant.MakeInt64(1))
+		}
+		// Cancel constant factors of the denominator: (c1*a) / (c2*b) = (c1/c2) * (a/b).
+		cx, tx := coeff(x)
+		cy, ty := coeff(y)
+		if !xok && !yok && !isValue(cy, 1) {
+			return simplifyBinary(numLit(constant.BinaryOp(cx, token.QUO, cy)), token.MUL, simplifyBinary(tx, token.QUO, ty))
+		}
+	}
+	return &ast.BinaryExpr{X: x, Op: op, Y: y}
+}
+
+// coeff returns the constant factor of the provided term, and the rest of the term.
+func coeff(expr ast.Expr) (constant.Value, ast.Expr) {
+	if x, ok := negated(expr); ok {
+		if _, ok := numVal(x); !ok {
+			c, t := coeff(x)
+			return constant.UnaryOp(token.SUB, c, 0), t
+		}
+	}
+	if b, ok := expr.(*ast.BinaryExpr); ok && b.Op == token.MUL {
+		if v, ok := numVal(b.X); ok {
+			return v, b.Y
+		}
+	}
+	return constant.MakeInt64(1), expr
+}
+
+// negated returns the operand of the provided expression if it is a negation.
+func negated(expr ast.Expr) (ast.Expr, bool) {
+	u, ok := expr.(*ast.UnaryExpr)
+	if !ok || u.Op != token.SUB {
+		return nil, false
+	}
+	return u.X, true
+}
+
+// equal returns whether the provided expressions are identical.
+func equal(x, y ast.Expr) bool {
+	return types.ExprString(parens(x)) == types.ExprString(parens(y))
+}
+
+// numVal returns the value of the provided numeric literal, possibly negated.
+// Integer literals have integer values.
+func numVal(expr ast.Expr) (constant.Value, bool) {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+		v, ok := numVal(u.X)
+		if !ok {
+			return nil, false
+		}
+		return constant.UnaryOp(token.SUB, v, 0), true
+	}
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT && lit.Kind != token.FLOAT {
+		return nil, false
+	}
+	return constant.MakeFromLiteral(lit.Value, lit.Kind, 0), true
+}
+
+// isNum returns whether the provided expression is the numeric literal v.
+func isNum(expr ast.Expr, v int64) bool {
+	x, ok := numVal(expr)
+	return ok && isValue(x, v)
+}
+
+// isValue returns whether the provided numeric value is v.
+func isValue(x constant.Value, v int64) bool {
+	return constant.Compare(x, token.EQL, constant.MakeInt64(v))
+}
+
+// numLit returns the literal of the provided numeric value.
+// Negative values are negated literals.
+func numLit(v constant.Value) ast.Expr {
+	if constant.Sign(v) < 0 {
+		return &ast.UnaryExpr{Op: token.SUB, X: numLit(constant.UnaryOp(token.SUB, v, 0))}
+	}
+	if v.Kind() == constant.Int {
+		return &ast.BasicLit{Kind: token.INT, Value: v.ExactString()}
+	}
+	f, _ := constant.Float64Val(v)
+	if f == math.Trunc(f) && f < 1e21 {
+		// Integral values are written without exponent, as in 2 for 2.0,
+		// but remain floating-point literals when folded.
+		return &ast.BasicLit{Kind: token.FLOAT, Value: strconv.FormatFloat(f, 'f', -1, 64)}
+	}
+	return &ast.BasicLit{Kind: token.FLOAT, Value: strconv.FormatFloat(f, 'g', -1, 64)}
+}
+
+// floatLit returns the floating-point literal of the provided numeric value,
+// as in 2.0 for 2. Negative values are negated literals.
+func floatLit(v constant.Value) ast.Expr {
+	if constant.Sign(v) < 0 {
+		return &ast.UnaryExpr{Op: token.SUB, X: floatLit(constant.UnaryOp(token.SUB, v, 0))}
+	}
+	f, _ := constant.Float64Val(v)
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return &ast.BasicLit{Kind: token.FLOAT, Value: s}
+}
+
+// one returns the literal 1.
+func one() ast.Expr {
+	return numLit(constant.MakeInt64(1))
+}
+
+// binary returns the binary expression x op y.
+func binary(x ast.Expr, op token.Token, y ast.Expr) ast.Expr {
+	return &ast.BinaryExpr{X: x, Op: op, Y: y}
+}
+
+// mathCall returns a call to the named function of the math package.
+func mathCall(name string, args ...ast.Expr) ast.Expr {
+	return &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent("math"), Sel: ast.NewIdent(name)},
+		Args: args,
+	}
+}
+
+// mathConst returns the named constant of the math package.
+func mathConst(name string) ast.Expr {
+	return &ast.SelectorExpr{X: ast.NewIdent("math"), Sel: ast.NewIdent(name)}
+}
+
+// parens returns the provided expression with the parentheses required
+// by the precedence of its operators.
+func parens(expr ast.Expr) ast.Expr {
+	paren := func(x ast.Expr, p int) ast.Expr {
+		b, ok := x.(*ast.BinaryExpr)
+		x = parens(x)
+		if ok && b.Op.Precedence() < p {
+			return &ast.ParenExpr{X: x}
+		}
+		return x
+	}
+
+	switch expr := expr.(type) {
+	case *ast.BinaryExpr:
+		p := expr.Op.Precedence()
+		x := paren(expr.X, p)
+		y := paren(expr.Y, p)
+		// Only sums and products are associative.
+		if b, ok := expr.Y.(*ast.BinaryExpr); ok && b.Op.Precedence() == p &&
+			!(b.Op == expr.Op && (b.Op == token.ADD || b.Op == token.MUL)) {
+			y = &ast.ParenExpr{X: y}
+		}
+		return &ast.BinaryExpr{X: x, Op: expr.Op, Y: y}
+	case *ast.UnaryExpr:
+		x := parens(expr.X)
+		if b, ok := expr.X.(*ast.BinaryExpr); ok &&
+			!(expr.Op == token.SUB && (b.Op == token.MUL || b.Op == token.QUO)) {
+			x = &ast.ParenExpr{X: x}
+		}
+		return &ast.UnaryExpr{Op: expr.Op, X: x}
+	case *ast.CallExpr:
+		args := make([]ast.Expr, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = parens(arg)
+		}
+		return &ast.CallExpr{Fun: expr.Fun, Args: args}
+	case *ast.IndexExpr:
+		return &ast.IndexExpr{X: parens(expr.X), Index: parens(expr.Index)}
+	case *ast.ParenExpr:
+		return parens(expr.X)
+	}
+	return expr
+}
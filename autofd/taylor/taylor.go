@@ -0,0 +1,390 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package taylor provides truncated Taylor series arithmetic, used by autofd
+// to generate derivatives of arbitrary order.
+package taylor // import "gonum.org/v1/tools/autofd/taylor"
+
+import "math"
+
+// Number is a truncated Taylor series.
+// The k-th element holds the k-th Taylor coefficient, f^(k)/k!.
+//
+// The result of an operation has the length of its longest operand,
+// missing coefficients being treated as zeros. A constant can thus be
+// represented as Number{c} and a variable x seeded up to order n as Var(x, n).
+type Number []float64
+
+// Var returns the Taylor series of the variable x, truncated at order n.
+func Var(x float64, n int) Number {
+	v := make(Number, n+1)
+	v[0] = x
+	if n > 0 {
+		v[1] = 1
+	}
+	return v
+}
+
+// Deriv returns the k-th derivative held by x.
+func (x Number) Deriv(k int) float64 {
+	if k >= len(x) {
+		return 0
+	}
+	f := 1.0
+	for i := 2; i <= k; i++ {
+		f *= float64(i)
+	}
+	return f * x[k]
+}
+
+// at returns the k-th coefficient of x.
+func (x Number) at(k int) float64 {
+	if k >= len(x) {
+		return 0
+	}
+	return x[k]
+}
+
+// size returns the length of the result of an operation on x and y.
+func size(x, y Number) int {
+	n := len(x)
+	if len(y) > n {
+		n = len(y)
+	}
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+// Add returns the sum of x and y.
+func Add(x, y Number) Number {
+	v := make(Number, size(x, y))
+	for k := range v {
+		v[k] = x.at(k) + y.at(k)
+	}
+	return v
+}
+
+// Sub returns the difference of x and y, x-y.
+func Sub(x, y Number) Number {
+	v := make(Number, size(x, y))
+	for k := range v {
+		v[k] = x.at(k) - y.at(k)
+	}
+	return v
+}
+
+// Scale returns x scaled by f.
+func Scale(f float64, x Number) Number {
+	v := make(Number, size(x, nil))
+	for k := range v {
+		v[k] = f * x.at(k)
+	}
+	return v
+}
+
+// Mul returns the product of x and y.
+func Mul(x, y Number) Number {
+	v := make(Number, size(x, y))
+	for k := range v {
+		for i := 0; i <= k; i++ {
+			v[k] += x.at(i) * y.at(k-i)
+		}
+	}
+	return v
+}
+
+// Inv returns the reciprocal of x.
+func Inv(x Number) Number {
+	v := make(Number, size(x, nil))
+	v[0] = 1 / x.at(0)
+	for k := 1; k < len(v); k++ {
+		var s float64
+		for i := 1; i <= k; i++ {
+			s += x.at(i) * v[k-i]
+		}
+		v[k] = -s * v[0]
+	}
+	return v
+}
+
+// Abs returns the absolute value of x.
+func Abs(x Number) Number {
+	if x.at(0) < 0 {
+		return Scale(-1, x)
+	}
+	return Scale(1, x)
+}
+
+// Exp returns e**x, the base-e exponential of x.
+func Exp(x Number) Number {
+	v := make(Number, size(x, nil))
+	v[0] = math.Exp(x.at(0))
+	for k := 1; k < len(v); k++ {
+		for i := 1; i <= k; i++ {
+			v[k] += float64(i) * x.at(i) * v[k-i]
+		}
+		v[k] /= float64(k)
+	}
+	return v
+}
+
+// Expm1 returns e**x - 1, the base-e exponential of x minus 1.
+func Expm1(x Number) Number {
+	v := Exp(x)
+	v[0] = math.Expm1(x.at(0))
+	return v
+}
+
+// Log returns the natural logarithm of x.
+func Log(x Number) Number {
+	v := make(Number, size(x, nil))
+	x0 := x.at(0)
+	v[0] = math.Log(x0)
+	for k := 1; k < len(v); k++ {
+		var s float64
+		for i := 1; i < k; i++ {
+			s += float64(i) * v[i] * x.at(k-i)
+		}
+		v[k] = (x.at(k) - s/float64(k)) / x0
+	}
+	return v
+}
+
+// Log1p returns the natural logarithm of 1 plus x.
+func Log1p(x Number) Number {
+	d := Inv(Add(Number{1}, x))
+	return integ(math.Log1p(x.at(0)), x, d)
+}
+
+// Log2 returns the binary logarithm of x.
+func Log2(x Number) Number {
+	v := Scale(1/math.Ln2, Log(x))
+	v[0] = math.Log2(x.at(0))
+	return v
+}
+
+// Log10 returns the decimal logarithm of x.
+func Log10(x Number) Number {
+	v := Scale(1/math.Ln10, Log(x))
+	v[0] = math.Log10(x.at(0))
+	return v
+}
+
+// PowReal returns x**p, the base-x exponential of p.
+func PowReal(x Number, p float64) Number {
+	if p >= 0 && p == math.Trunc(p) && p <= math.MaxInt32 {
+		// Use exact products for non-negative integer powers,
+		// as the recurrence below is undefined when x[0] is zero.
+		v := make(Number, size(x, nil))
+		v[0] = 1
+		for b, n := x, int(p); n > 0; n >>= 1 {
+			if n&1 == 1 {
+				v = Mul(v, b)
+			}
+			b = Mul(b, b)
+		}
+		return v
+	}
+	return pow(x, p, math.Pow(x.at(0), p))
+}
+
+// pow returns x**p, given v0 = x[0]**p.
+func pow(x Number, p, v0 float64) Number {
+	v := make(Number, size(x, nil))
+	x0 := x.at(0)
+	v[0] = v0
+	for k := 1; k < len(v); k++ {
+		for i := 1; i <= k; i++ {
+			v[k] += ((p+1)*float64(i) - float64(k)) * x.at(i) * v[k-i]
+		}
+		v[k] /= float64(k) * x0
+	}
+	return v
+}
+
+// Pow returns x**p, the base-x exponential of p.
+func Pow(x, p Number) Number {
+	for k := 1; k < len(p); k++ {
+		if p[k] != 0 {
+			return Exp(Mul(p, Log(x)))
+		}
+	}
+	return PowReal(x, p.at(0))
+}
+
+// Sqrt returns the square root of x.
+func Sqrt(x Number) Number {
+	return PowReal(x, 0.5)
+}
+
+// Cbrt returns the cube root of x.
+func Cbrt(x Number) Number {
+	return pow(x, 1.0/3, math.Cbrt(x.at(0)))
+}
+
+// Hypot returns Sqrt(p*p + q*q).
+func Hypot(p, q Number) Number {
+	v := PowReal(Add(Mul(p, p), Mul(q, q)), 0.5)
+	v[0] = math.Hypot(p.at(0), q.at(0))
+	return v
+}
+
+// Sin returns the sine of x.
+func Sin(x Number) Number {
+	s, _ := sincos(x, -1)
+	return s
+}
+
+// Cos returns the cosine of x.
+func Cos(x Number) Number {
+	_, c := sincos(x, -1)
+	return c
+}
+
+// Tan returns the tangent of x.
+func Tan(x Number) Number {
+	s, c := sincos(x, -1)
+	return Mul(s, Inv(c))
+}
+
+// Sinh returns the hyperbolic sine of x.
+func Sinh(x Number) Number {
+	s, _ := sincos(x, +1)
+	return s
+}
+
+// Cosh returns the hyperbolic cosine of x.
+func Cosh(x Number) Number {
+	_, c := sincos(x, +1)
+	return c
+}
+
+// Tanh returns the hyperbolic tangent of x.
+func Tanh(x Number) Number {
+	s, c := sincos(x, +1)
+	return Mul(s, Inv(c))
+}
+
+// sincos returns the sine and cosine of x if sign is -1,
+// and their hyperbolic counterparts if sign is +1.
+func sincos(x Number, sign float64) (s, c Number) {
+	n := size(x, nil)
+	s = make(Number, n)
+	c = make(Number, n)
+	x0 := x.at(0)
+	switch sign {
+	case -1:
+		s[0], c[0] = math.Sincos(x0)
+	default:
+		s[0], c[0] = math.Sinh(x0), math.Cosh(x0)
+	}
+	for k := 1; k < n; k++ {
+		for i := 1; i <= k; i++ {
+			s[k] += float64(i) * x.at(i) * c[k-i]
+			c[k] += float64(i) * x.at(i) * s[k-i]
+		}
+		s[k] /= float64(k)
+		c[k] *= sign / float64(k)
+	}
+	return s, c
+}
+
+// Asin returns the inverse sine of x.
+func Asin(x Number) Number {
+	d := PowReal(Sub(Number{1}, Mul(x, x)), -0.5)
+	return integ(math.Asin(x.at(0)), x, d)
+}
+
+// Acos returns the inverse cosine of x.
+func Acos(x Number) Number {
+	d := Scale(-1, PowReal(Sub(Number{1}, Mul(x, x)), -0.5))
+	return integ(math.Acos(x.at(0)), x, d)
+}
+
+// Atan returns the inverse tangent of x.
+func Atan(x Number) Number {
+	d := Inv(Add(Number{1}, Mul(x, x)))
+	return integ(math.Atan(x.at(0)), x, d)
+}
+
+// Asinh returns the inverse hyperbolic sine of x.
+func Asinh(x Number) Number {
+	d := PowReal(Add(Mul(x, x), Number{1}), -0.5)
+	return integ(math.Asinh(x.at(0)), x, d)
+}
+
+// Acosh returns the inverse hyperbolic cosine of x.
+func Acosh(x Number) Number {
+	d := PowReal(Sub(Mul(x, x), Number{1}), -0.5)
+	return integ(math.Acosh(x.at(0)), x, d)
+}
+
+// Atanh returns the inverse hyperbolic tangent of x.
+func Atanh(x Number) Number {
+	d := Inv(Sub(Number{1}, Mul(x, x)))
+	return integ(math.Atanh(x.at(0)), x, d)
+}
+
+// Atan2 returns the arc tangent of y/x, using the signs of the two
+// to determine the quadrant of the return value.
+func Atan2(y, x Number) Number {
+	// The derivative of atan2(y, x) is (x*y' - y*x') / (x*x + y*y).
+	d := Mul(Sub(Mul(x, deriv(y)), Mul(y, deriv(x))), Inv(Add(Mul(x, x), Mul(y, y))))
+	v := make(Number, size(x, y))
+	v[0] = math.Atan2(y.at(0), x.at(0))
+	for k := 1; k < len(v); k++ {
+		v[k] = d.at(k-1) / float64(k)
+	}
+	return v
+}
+
+// Erf returns the error function of x.
+func Erf(x Number) Number {
+	d := Scale(2/math.SqrtPi, Exp(Scale(-1, Mul(x, x))))
+	return integ(math.Erf(x.at(0)), x, d)
+}
+
+// Erfc returns the complementary error function of x.
+func Erfc(x Number) Number {
+	d := Scale(-2/math.SqrtPi, Exp(Scale(-1, Mul(x, x))))
+	return integ(math.Erfc(x.at(0)), x, d)
+}
+
+// deriv returns the Taylor series of the derivative of x.
+func deriv(x Number) Number {
+	n := len(x) - 1
+	if n < 1 {
+		n = 1
+	}
+	v := make(Number, n)
+	for k := range v {
+		v[k] = float64(k+1) * x.at(k+1)
+	}
+	return v
+}
+
+// integ returns the Taylor series of f(x), given f0 = f(x[0]) and
+// the Taylor series d of f'(x).
+func integ(f0 float64, x, d Number) Number {
+	v := make(Number, size(x, nil))
+	v[0] = f0
+	for k := 1; k < len(v); k++ {
+		for i := 1; i <= k; i++ {
+			v[k] += float64(i) * x.at(i) * d.at(k-i)
+		}
+		v[k] /= float64(k)
+	}
+	return v
+}
+
+// Mod returns the floating-point remainder of x/y, x - q*y where q is
+// x/y truncated toward zero. The quotient q is held constant: the
+// derivatives of Mod do not account for its jumps.
+func Mod(x, y Number) Number {
+	v := Sub(x, Scale(math.Trunc(x.at(0)/y.at(0)), y))
+	v[0] = math.Mod(x.at(0), y.at(0))
+	return v
+}
@@ -0,0 +1,233 @@
+// Copyright ©2020 The Gonum Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taylor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDeriv(t *testing.T) {
+	const tol = 1e-12
+	for _, test := range []struct {
+		name string
+		fn   func(x Number) Number
+		x    float64
+		want []float64 // f(x), f'(x), f''(x), f'''(x)
+	}{
+		{
+			name: "x^3",
+			fn:   func(x Number) Number { return Mul(x, Mul(x, x)) },
+			x:    2,
+			want: []float64{8, 12, 12, 6},
+		},
+		{
+			name: "x^3+1/x",
+			fn:   func(x Number) Number { return Add(PowReal(x, 3), Inv(x)) },
+			x:    2,
+			want: []float64{8.5, 12 - 1.0/4, 12 + 2.0/8, 6 - 6.0/16},
+		},
+		{
+			name: "x^0",
+			fn:   func(x Number) Number { return PowReal(x, 0) },
+			x:    0,
+			want: []float64{1, 0, 0, 0},
+		},
+		{
+			name: "x-2*x",
+			fn:   func(x Number) Number { return Sub(x, Scale(2, x)) },
+			x:    3,
+			want: []float64{-3, -1, 0, 0},
+		},
+		{
+			name: "exp",
+			fn:   Exp,
+			x:    0.5,
+			want: []float64{math.Exp(0.5), math.Exp(0.5), math.Exp(0.5), math.Exp(0.5)},
+		},
+		{
+			name: "log",
+			fn:   Log,
+			x:    2,
+			want: []float64{math.Log(2), 0.5, -0.25, 0.25},
+		},
+		{
+			name: "sqrt",
+			fn:   Sqrt,
+			x:    4,
+			want: []float64{2, 0.25, -1.0 / 32, 3.0 / 256},
+		},
+		{
+			name: "pow",
+			fn:   func(x Number) Number { return Pow(x, x) },
+			x:    1,
+			want: []float64{1, 1, 2, 3},
+		},
+		{
+			name: "abs",
+			fn:   Abs,
+			x:    -2,
+			want: []float64{2, -1, 0, 0},
+		},
+		{
+			name: "sin",
+			fn:   Sin,
+			x:    0.5,
+			want: []float64{math.Sin(0.5), math.Cos(0.5), -math.Sin(0.5), -math.Cos(0.5)},
+		},
+		{
+			name: "cos",
+			fn:   Cos,
+			x:    0.5,
+			want: []float64{math.Cos(0.5), -math.Sin(0.5), -math.Cos(0.5), math.Sin(0.5)},
+		},
+		{
+			name: "tan",
+			fn:   Tan,
+			x:    0,
+			want: []float64{0, 1, 0, 2},
+		},
+		{
+			name: "sinh",
+			fn:   Sinh,
+			x:    0.5,
+			want: []float64{math.Sinh(0.5), math.Cosh(0.5), math.Sinh(0.5), math.Cosh(0.5)},
+		},
+		{
+			name: "cosh",
+			fn:   Cosh,
+			x:    0.5,
+			want: []float64{math.Cosh(0.5), math.Sinh(0.5), math.Cosh(0.5), math.Sinh(0.5)},
+		},
+		{
+			name: "tanh",
+			fn:   Tanh,
+			x:    0,
+			want: []float64{0, 1, 0, -2},
+		},
+		{
+			name: "asin",
+			fn:   Asin,
+			x:    0.5,
+			want: []float64{math.Asin(0.5), 1 / math.Sqrt(0.75), 0.5 / math.Pow(0.75, 1.5), 1.5 / math.Pow(0.75, 2.5)},
+		},
+		{
+			name: "acos",
+			fn:   Acos,
+			x:    0.5,
+			want: []float64{math.Acos(0.5), -1 / math.Sqrt(0.75), -0.5 / math.Pow(0.75, 1.5), -1.5 / math.Pow(0.75, 2.5)},
+		},
+		{
+			name: "atan",
+			fn:   Atan,
+			x:    1,
+			want: []float64{math.Pi / 4, 0.5, -0.5, 0.5},
+		},
+		{
+			name: "asinh",
+			fn:   Asinh,
+			x:    0,
+			want: []float64{0, 1, 0, -1},
+		},
+		{
+			name: "acosh",
+			fn:   Acosh,
+			x:    2,
+			want: []float64{math.Acosh(2), 1 / math.Sqrt(3), -2 / math.Pow(3, 1.5), 9 / math.Pow(3, 2.5)},
+		},
+		{
+			name: "atanh",
+			fn:   Atanh,
+			x:    0,
+			want: []float64{0, 1, 0, 2},
+		},
+		{
+			name: "log1p",
+			fn:   Log1p,
+			x:    1,
+			want: []float64{math.Log(2), 0.5, -0.25, 0.25},
+		},
+		{
+			name: "expm1",
+			fn:   Expm1,
+			x:    1e-10,
+			want: []float64{math.Expm1(1e-10), math.Exp(1e-10), math.Exp(1e-10), math.Exp(1e-10)},
+		},
+		{
+			name: "log2",
+			fn:   Log2,
+			x:    2,
+			want: []float64{1, 0.5 / math.Ln2, -0.25 / math.Ln2, 0.25 / math.Ln2},
+		},
+		{
+			name: "log10",
+			fn:   Log10,
+			x:    10,
+			want: []float64{1, 0.1 / math.Ln10, -0.01 / math.Ln10, 0.002 / math.Ln10},
+		},
+		{
+			name: "cbrt",
+			fn:   Cbrt,
+			x:    -8,
+			want: []float64{-2, 1.0 / 12, 1.0 / 144, 10.0 / 6912},
+		},
+		{
+			name: "erf",
+			fn:   Erf,
+			x:    0,
+			want: []float64{0, 2 / math.SqrtPi, 0, -4 / math.SqrtPi},
+		},
+		{
+			name: "erfc",
+			fn:   Erfc,
+			x:    0,
+			want: []float64{1, -2 / math.SqrtPi, 0, 4 / math.SqrtPi},
+		},
+		{
+			name: "atan2(x,2)",
+			fn:   func(x Number) Number { return Atan2(x, Number{2}) },
+			x:    0,
+			want: []float64{0, 0.5, 0, -0.25},
+		},
+		{
+			name: "atan2(1,x)",
+			fn:   func(x Number) Number { return Atan2(Number{1}, x) },
+			x:    1,
+			want: []float64{math.Pi / 4, -0.5, 0.5, -0.5},
+		},
+		{
+			name: "hypot(x,x)",
+			fn:   func(x Number) Number { return Hypot(x, x) },
+			x:    2,
+			want: []float64{2 * math.Sqrt2, math.Sqrt2, 0, 0},
+		},
+		{
+			name: "mod(x*x,2)",
+			fn:   func(x Number) Number { return Mod(Mul(x, x), Number{2}) },
+			x:    1.5,
+			want: []float64{0.25, 3, 2, 0},
+		},
+	} {
+		v := test.fn(Var(test.x, len(test.want)-1))
+		if len(v) != len(test.want) {
+			t.Errorf("unexpected length for %s: got=%d want=%d", test.name, len(v), len(test.want))
+			continue
+		}
+		for k, want := range test.want {
+			if got := v.Deriv(k); math.Abs(got-want) > tol*math.Max(1, math.Abs(want)) {
+				t.Errorf("unexpected derivative %d of %s at %v: got=%v want=%v", k, test.name, test.x, got, want)
+			}
+		}
+	}
+}
+
+func TestConstant(t *testing.T) {
+	v := Add(Number{2}, Mul(Number{3}, Var(1, 2)))
+	for k, want := range []float64{5, 3, 0, 0} {
+		if got := v.Deriv(k); got != want {
+			t.Errorf("unexpected derivative %d: got=%v want=%v", k, got, want)
+		}
+	}
+}
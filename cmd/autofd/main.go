@@ -7,10 +7,12 @@
 package main // import "gonum.org/v1/tools/cmd/autofd"
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"gonum.org/v1/tools/autofd"
 )
@@ -20,9 +22,15 @@ func main() {
 	log.SetFlags(0)
 
 	pkg := flag.String("pkg", "", "import path of the package holding the function or method definition")
-	fct := flag.String("fct", "", "name of the function or method definition")
-	d2 := flag.Bool("d2", false, "whether to generate both first and second derivatives")
+	fct := flag.String("fct", "", "name of the function, method or func variable definition, with type arguments for generic functions (e.g. F[float64])")
+	order := flag.Int("order", 1, "order of the highest derivative to generate (2 for the Hessian of multivariate functions)")
 	der := flag.String("der", "", "name of the derivative to generate")
+	wrt := flag.String("wrt", "", "name of the parameter to differentiate against")
+	mode := flag.String("mode", "forward", "differentiation mode (forward, reverse, symbolic)")
+	subgrad := flag.String("subgradient", "average", "derivatives of math.Max and math.Min at kinks (average, left, right, zero)")
+	out := flag.String("o", "", "path of the Go source file to write (default: stdout)")
+	name := flag.String("package", "", "package clause of the Go source file (default: package of the function)")
+	test := flag.Bool("test", false, "also write a _test.go file checking the derivatives against finite differences")
 
 	flag.Usage = func() {
 		fmt.Fprintf(
@@ -32,24 +40,211 @@ func main() {
 ex:
  $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F1
  func DerivF1(x float64) float64 {
- 	v := dual.Mul(dual.Number{Real:x, Emag:1}, dual.Number{Real:x, Emag:1})
+ 	xd := dual.Number{Real:x, Emag:1}
+ 	v := dual.Mul(xd, xd)
  	return v.Emag
  }
 
- $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F1 -d2
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F1 -order 2
  func DerivF1(x float64) (d1, d2 float64) {
- 	v := hyperdual.Mul(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Number{Real:x, E1mag:1, E2mag:1})
+ 	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+ 	v := hyperdual.Mul(xd, xd)
  	return v.E1mag, v.E1E2mag
  }
 
- $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F1 -der DxF1 -d2
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F1 -der DxF1 -order 2
  func DxF1(x float64) (d1, d2 float64) {
- 	v := hyperdual.Mul(hyperdual.Number{Real:x, E1mag:1, E2mag:1}, hyperdual.Number{Real:x, E1mag:1, E2mag:1})
+ 	xd := hyperdual.Number{Real:x, E1mag:1, E2mag:1}
+ 	v := hyperdual.Mul(xd, xd)
  	return v.E1mag, v.E1E2mag
  }
 
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F1 -order 3
+ func DerivF1(x float64) (d1, d2, d3 float64) {
+ 	xd := taylor.Var(x, 3)
+ 	v := taylor.Mul(xd, xd)
+ 	return v.Deriv(1), v.Deriv(2), v.Deriv(3)
+ }
+
  $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct T1.F
 
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F21 -wrt y
+ func DerivF21_y(x float64, n int, y float64) float64 {
+ 	yd := dual.Number{Real:y, Emag:1}
+ 	v := dual.Scale(math.Pow(x, float64(n)), yd)
+ 	return v.Emag
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct C1
+ func DerivC1(z complex128) complex128 {
+ 	zd := cdual.Number{Real:z, Emag:1}
+ 	v := cdual.Add(cdual.Mul(zd, zd), cdual.Scale(2i, zd))
+ 	return v.Emag
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct 'P1[float32]'
+ func DerivP1_float32(x float32) float32 {
+ 	xd := dual.Number{Real:float64(x), Emag:1}
+ 	v := dual.Add(dual.Mul(dual.Scale(3, xd), xd), dual.Sin(xd))
+ 	return float32(v.Emag)
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct G1
+ func DerivG1(grad, x []float64) {
+ 	if len(grad) != len(x) {
+ 		panic("slice length mismatch")
+ 	}
+ 	fn := func(x []dual.Number) dual.Number {
+ 		return dual.Add(dual.Add(dual.Mul(x[0], x[0]), dual.Mul(dual.Scale(3, x[0]), x[1])), dual.Sin(x[1]))
+ 	}
+ 	xd := make([]dual.Number, len(x))
+ 	for i, v := range x {
+ 		xd[i].Real = v
+ 	}
+ 	for i := range xd {
+ 		xd[i].Emag = 1
+ 		grad[i] = fn(xd).Emag
+ 		xd[i].Emag = 0
+ 	}
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct G1 -order 2
+ func DerivG1(hess *mat.SymDense, x []float64) {
+ 	if n, _ := hess.Dims(); n != len(x) {
+ 		panic("matrix size mismatch")
+ 	}
+ 	fn := func(x []hyperdual.Number) hyperdual.Number {
+ 		return hyperdual.Add(hyperdual.Add(hyperdual.Mul(x[0], x[0]), hyperdual.Mul(hyperdual.Scale(3, x[0]), x[1])), hyperdual.Sin(x[1]))
+ 	}
+ 	xd := make([]hyperdual.Number, len(x))
+ 	for i, v := range x {
+ 		xd[i].Real = v
+ 	}
+ 	for i := range xd {
+ 		xd[i].E1mag = 1
+ 		for j := i; j < len(xd); j++ {
+ 			xd[j].E2mag = 1
+ 			hess.SetSym(i, j, fn(xd).E1E2mag)
+ 			xd[j].E2mag = 0
+ 		}
+ 		xd[i].E1mag = 0
+ 	}
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct J1
+ func DerivJ1(jac *mat.Dense, x []float64) {
+ 	m, n := jac.Dims()
+ 	if n != len(x) {
+ 		panic("matrix size mismatch")
+ 	}
+ 	fn := func(dst, x []dual.Number) {
+ 		dst[0] = dual.Mul(x[0], x[1])
+ 		dst[1] = dual.Add(dual.Sin(x[0]), dual.Mul(x[1], x[1]))
+ 		dst[2] = dual.Exp(dual.Sub(x[0], x[1]))
+ 	}
+ 	xd := make([]dual.Number, len(x))
+ 	for i, v := range x {
+ 		xd[i].Real = v
+ 	}
+ 	yd := make([]dual.Number, m)
+ 	for j := range xd {
+ 		xd[j].Emag = 1
+ 		fn(yd, xd)
+ 		for i, v := range yd {
+ 			jac.Set(i, j, v.Emag)
+ 		}
+ 		xd[j].Emag = 0
+ 	}
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct G1 -mode reverse
+ func DerivG1(grad, x []float64) {
+ 	if len(grad) != len(x) {
+ 		panic("slice length mismatch")
+ 	}
+ 	fn := func(x []adjoint.Number) adjoint.Number {
+ 		return adjoint.Add(adjoint.Add(adjoint.Mul(x[0], x[0]), adjoint.Mul(adjoint.Scale(3, x[0]), x[1])), adjoint.Sin(x[1]))
+ 	}
+ 	var tape adjoint.Tape
+ 	xd := make([]adjoint.Number, len(x))
+ 	for i, v := range x {
+ 		xd[i] = tape.Var(v)
+ 	}
+ 	tape.Gradient(grad, fn(xd))
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F3 -order 2 -mode symbolic
+ func DerivF3(x float64) (d1, d2 float64) {
+ 	return 4 * x, 4
+ }
+
+ $> autofd -pkg gonum.org/v1/tools/autofd/internal/testfunc -fct F1 -o zz_deriv.go
+ $> cat zz_deriv.go
+ // Code generated by autofd; DO NOT EDIT.
+
+ package testfunc
+
+ import (
+ 	"gonum.org/v1/gonum/num/dual"
+ )
+
+ func DerivF1(x float64) float64 {
+ 	xd := dual.Number{Real: x, Emag: 1}
+ 	v := dual.Mul(xd, xd)
+ 	return v.Emag
+ }
+
+If neither -o nor -package is set, only the derivative is printed.
+Otherwise, a complete, formatted, Go source file is generated. The derivatives
+refer to the package-level identifiers of the package of the function
+unqualified: -package may only name another package if they refer to none.
+
+Without -fct, the derivatives requested by //autofd:derive directives in the
+doc comments of the declarations of the package are written to a single file,
+zz_autofd.go by default. -pkg defaults to the package in the current directory,
+and only the -o and -test options apply:
+
+ $> cat funcs.go
+ package testfunc
+
+ //autofd:derive DxF1 -d2
+ func F1(x float64) float64 {
+ 	return x * x
+ }
+
+ //go:generate autofd
+ $> go generate
+ $> cat zz_autofd.go
+ // Code generated by autofd; DO NOT EDIT.
+
+ package testfunc
+
+ import (
+ 	"gonum.org/v1/gonum/num/hyperdual"
+ )
+
+ func DxF1(x float64) (d1, d2 float64) {
+ 	xd := hyperdual.Number{Real: x, E1mag: 1, E2mag: 1}
+ 	v := hyperdual.Mul(xd, xd)
+ 	return v.E1mag, v.E1E2mag
+ }
+
+With -test, a zz_autofd_test.go file, or the _test.go file matching -o, is also
+written: it checks the generated derivatives at sample points against the finite
+differences of the gonum.org/v1/gonum/diff/fd package.
+
+The directives are of the form:
+
+ //autofd:derive [name] [-d2] [-order n] [-wrt param] [-mode reverse|symbolic] [-subgradient left|right|zero]
+
+where name is the name of the derivative, and the options are as the options
+of the command. -d2 is a shorthand for -order 2.
+
+The derivatives of functions autofd can not follow may be declared with rule
+directives, in a comment of their package:
+
+ //autofd:rule MyFunc deriv=MyFuncPrime
+
 Options:
 `,
 		)
@@ -58,23 +253,148 @@ Options:
 
 	flag.Parse()
 
-	switch {
-	case *pkg == "":
+	if *fct == "" {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "pkg", "o", "test":
+			default:
+				flag.Usage()
+				log.Fatalf("invalid flag -%s without -fct: options are read from the //autofd:derive directives", f.Name)
+			}
+		})
+		generate(*pkg, *out, *test)
+		return
+	}
+	if *pkg == "" {
 		flag.Usage()
 		log.Fatalf("missing import path")
-	case *fct == "":
+	}
+
+	var m autofd.Mode
+	switch *mode {
+	case "forward":
+		m = autofd.Forward
+	case "reverse":
+		m = autofd.Reverse
+	case "symbolic":
+		m = autofd.Symbolic
+	default:
+		flag.Usage()
+		log.Fatalf("invalid differentiation mode %q", *mode)
+	}
+
+	var s autofd.Subgradient
+	switch *subgrad {
+	case "average":
+		s = autofd.AverageSubgradient
+	case "left":
+		s = autofd.LeftSubgradient
+	case "right":
+		s = autofd.RightSubgradient
+	case "zero":
+		s = autofd.ZeroSubgradient
+	default:
+		flag.Usage()
+		log.Fatalf("invalid subgradient %q", *subgrad)
+	}
+
+	f := autofd.Func{
+		Path:        *pkg,
+		Name:        *fct,
+		Deriv:       *der,
+		Wrt:         *wrt,
+		Mode:        m,
+		Subgradient: s,
+	}
+
+	if *test && *out == "" {
 		flag.Usage()
-		log.Fatalf("missing function or method name")
+		log.Fatalf("missing output file for -test")
+	}
+
+	if *out == "" && *name == "" {
+		err := autofd.Derivative(os.Stdout, f, *order)
+		if err != nil {
+			log.Fatalf("could not generate derivative of %s.%s: %+v",
+				*pkg, *fct, err,
+			)
+		}
+		return
 	}
 
-	err := autofd.Derivative(os.Stdout, autofd.Func{
-		Path:  *pkg,
-		Name:  *fct,
-		Deriv: *der,
-	}, *d2)
+	buf := new(bytes.Buffer)
+	err := autofd.File(buf, *name, []autofd.Func{f}, *order)
 	if err != nil {
 		log.Fatalf("could not generate derivative of %s.%s: %+v",
 			*pkg, *fct, err,
 		)
 	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*out, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		log.Fatalf("could not write derivative of %s.%s: %+v",
+			*pkg, *fct, err,
+		)
+	}
+
+	if !*test {
+		return
+	}
+	buf.Reset()
+	err = autofd.FileTest(buf, *name, []autofd.Func{f}, *order)
+	if err != nil {
+		log.Fatalf("could not generate test of derivative of %s.%s: %+v",
+			*pkg, *fct, err,
+		)
+	}
+	err = os.WriteFile(testFile(*out), buf.Bytes(), 0644)
+	if err != nil {
+		log.Fatalf("could not write test of derivative of %s.%s: %+v",
+			*pkg, *fct, err,
+		)
+	}
+}
+
+// generate writes the derivatives requested by the directives of the package
+// with the provided import path to the provided file, and their tests
+// if test is true.
+func generate(pkg, out string, test bool) {
+	if pkg == "" {
+		pkg = "."
+	}
+	if out == "" {
+		out = "zz_autofd.go"
+	}
+
+	buf := new(bytes.Buffer)
+	err := autofd.Package(buf, pkg)
+	if err != nil {
+		log.Fatalf("could not generate derivatives of %s: %+v", pkg, err)
+	}
+	err = os.WriteFile(out, buf.Bytes(), 0644)
+	if err != nil {
+		log.Fatalf("could not write derivatives of %s: %+v", pkg, err)
+	}
+
+	if !test {
+		return
+	}
+	buf.Reset()
+	err = autofd.PackageTest(buf, pkg)
+	if err != nil {
+		log.Fatalf("could not generate tests of derivatives of %s: %+v", pkg, err)
+	}
+	err = os.WriteFile(testFile(out), buf.Bytes(), 0644)
+	if err != nil {
+		log.Fatalf("could not write tests of derivatives of %s: %+v", pkg, err)
+	}
+}
+
+// testFile returns the name of the test file of the provided Go source file.
+func testFile(name string) string {
+	return strings.TrimSuffix(name, ".go") + "_test.go"
 }
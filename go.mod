@@ -1,6 +1,6 @@
 module gonum.org/v1/tools
 
-go 1.13
+go 1.18
 
 require (
 	github.com/mattn/goveralls v0.0.5